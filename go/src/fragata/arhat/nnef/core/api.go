@@ -71,6 +71,10 @@ func(d *ValueDict) At(idx int) Value {
     return d.items[idx].value
 }
 
+func(d *ValueDict) KeyAt(idx int) string {
+    return d.items[idx].key
+}
+
 //
 //    Tensor
 //
@@ -120,7 +124,7 @@ func(t *Tensor) ResizeData() {
         t.data = make([]float32, volume)
     case "integer":
         t.data = make([]int, volume)
-    case "bool":
+    case "logical":
         t.data = make([]bool, volume)
     default:
         t.data = nil
@@ -194,6 +198,10 @@ func(o *Operation) AttribAt(idx int) Value {
     return o.attribs.At(idx)
 }
 
+func(o *Operation) AttribNameAt(idx int) string {
+    return o.attribs.KeyAt(idx)
+}
+
 func(o *Operation) AddInput(name string, value Value) {
     o.inputs.Add(name, value)
 }
@@ -162,8 +162,8 @@ func ReadExtensions(lexer *Lexer, handler func(string) bool) Extensions {
             if !lexer.ReadIfToken(Token(',')) {
                 break
             }
-        lexer.ReadToken(Token(';'))
         }
+        lexer.ReadToken(Token(';'))
     }
     return extensions
 }
@@ -642,10 +642,18 @@ func SampleLikeShape(
         dilation Value,
         outputShape Value,
         transposed bool) Shape {
-    Check(index.Eq(input), 
-        "index shape incompatible with input shape (%s vs %s)", 
-            index.String(), input.String())
-    return PoolLikeShape(input, size, border, padding, stride, dilation, outputShape, transposed)
+    output := PoolLikeShape(input, size, border, padding, stride, dilation, outputShape, transposed)
+    // index refers to the pooled (smaller) tensor in both directions
+    if transposed {
+        Check(index.Eq(input), 
+            "index shape incompatible with input shape (%s vs %s)", 
+                index.String(), input.String())
+    } else {
+        Check(index.Eq(output), 
+            "index shape incompatible with output shape (%s vs %s)", 
+                index.String(), output.String())
+    }
+    return output
 }
 
 func PoolShape(
@@ -992,50 +1000,74 @@ func PadShape(input Shape, padding Value) Shape {
     return output
 }
 
+// Batch dimensions of A and B are aligned from the right, i.e. operand
+// of lower rank is treated as having leading singleton dimensions.
+
 func MatmulShape(a Shape, b Shape, trA Value, trB Value) Shape {
     aSize := len(a)
     bSize := len(b)
-    Check(aSize == bSize, "rank mismatch for A and B (%d vs %d)", aSize, bSize)
-    rank := aSize
-    Check(rank >= 2, "rank of A and B must be at least 2, found %d", rank)
+    Check(aSize >= 2, "rank of A must be at least 2, found %d", aSize)
+    Check(bSize >= 2, "rank of B must be at least 2, found %d", bSize)
+    rank := IntMax(aSize, bSize)
     batchDims := rank - 2
-    Check(BroadcastCompatibleN(a, b, batchDims),
-        "incompatible tensor shapes for broadcasting first %d dimensions (%s vs %s)",
-            batchDims, a.String(), b.String())
-    i0 := batchDims
-    i1 := batchDims + 1
+    aBatch := MatmulBatchShape(a, batchDims)
+    bBatch := MatmulBatchShape(b, batchDims)
+    Check(BroadcastCompatibleN(aBatch, bBatch, batchDims),
+        "incompatible tensor shapes for broadcasting batch dimensions (%s vs %s)",
+            a.String(), b.String())
     var m, n, kA, kB int
     if trA.Logical() {
-        m = a[i1]
-        kA = a[i0]
+        m = a[aSize-1]
+        kA = a[aSize-2]
     } else {
-        m = a[i0]
-        kA = a[i1]
+        m = a[aSize-2]
+        kA = a[aSize-1]
     }
     if trB.Logical() {
-        n = b[i0]
-        kB = b[i1]
+        n = b[bSize-2]
+        kB = b[bSize-1]
     } else {
-        n = b[i1]
-        kB = b[i0]
+        n = b[bSize-1]
+        kB = b[bSize-2]
     }
     Check(kA == kB, "inner dimensions must agree (%d vs %d)", kA, kB)
-    c := BroadcastShapeN(a, b, batchDims)
-    c[i0] = m
-    c[i1] = n
+    c := BroadcastShapeN(aBatch, bBatch, batchDims)
+    c = append(c, m, n)
     return c
 }
 
+// Returns batch dimensions of matmul operand extended with leading
+// singleton dimensions to batchDims.
+
+func MatmulBatchShape(shape Shape, batchDims int) Shape {
+    size := len(shape) - 2
+    result := make(Shape, batchDims)
+    lead := batchDims - size
+    for i := 0; i < lead; i++ {
+        result[i] = 1
+    }
+    copy(result[lead:], shape[:size])
+    return result
+}
+
+// Leading dimensions of input are flattened, i.e. linear is applied
+// along the last dimension.
+
 func LinearShape(input Shape, filter Shape, bias Shape) Shape {
-    Check(len(input) == 2, "input shape must be of rank 2 (found %d)", len(input))
+    rank := len(input)
+    Check(rank >= 1, "input shape must be of rank at least 1 (found %d)", rank)
     Check(len(filter) == 2, "filter shape must be of rank 2 (found %d)", len(filter))
-    Check(input[1] == filter[1], "inner dimensions must agree (%d vs %d)", input[1], filter[1])
+    Check(input[rank-1] == filter[1], 
+        "inner dimensions must agree (%d vs %d)", input[rank-1], filter[1])
     if len(bias) != 0 {
         Check(len(bias) == 2, "bias shape must be of rank 2 (found %d)", len(bias))
+        Check(bias[0] == 1, "bias shape must be singular for the batch dimension")
         Check(bias[1] == filter[0],
             "bias channels (%d) does not match filter count (%d)", bias[1], filter[0])
-        }
-    return Shape{input[0], filter[0]}
+    }
+    output := input.Clone()
+    output[rank-1] = filter[0]
+    return output
 }
 
 func UpdateShape(variable Shape, value Shape) Shape {
@@ -22,6 +22,8 @@
 
 package api
 
+import "errors"
+
 //
 //    Dtype
 //
@@ -66,6 +68,17 @@ const (
     OpSoftplus
 )
 
+//
+//    UnaryParamOp
+//
+
+type UnaryParamOp int
+
+const (
+    OpLeakyRelu UnaryParamOp = iota
+    OpSoftabs
+)
+
 //
 //    BinaryOp
 //
@@ -88,6 +101,7 @@ const (
     OpGe
     OpEq
     OpNe
+    OpPrelu
 )
 
 //
@@ -128,10 +142,64 @@ const (
     OpMaxPool
 )
 
+//
+//    LocalNormOp
+//
+
+type LocalNormOp int
+
+const (
+    OpLocalResponseNorm LocalNormOp = iota
+    OpLocalMeanNorm
+    OpLocalVarianceNorm
+    OpLocalContrastNorm
+)
+
+//
+//    NormOp
+//
+
+type NormOp int
+
+const (
+    OpL1Norm NormOp = iota
+    OpL2Norm
+)
+
+//
+//    Border
+//
+
+type Border int
+
+const (
+    BorderConstant Border = iota
+    BorderIgnore
+    BorderReplicate
+    BorderReflect
+    BorderReflectEven
+)
+
+//
+//    UpsampleMethod
+//
+
+type UpsampleMethod int
+
+const (
+    UpsampleSymmetric UpsampleMethod = iota
+    UpsampleAsymmetric
+    UpsampleAligned
+)
+
 //
 //    Tensor
 //
 
+// Largest tensor rank that engines must support
+
+const MaxRank = 8
+
 type Tensor interface {
     Dtype() Dtype
     Rank() int
@@ -139,6 +207,59 @@ type Tensor interface {
     Shape() []int
 }
 
+//
+//    Capabilities
+//
+
+// Optional interface of engines that report operations they implement
+// natively. Operations are identified by names of NNEF stdlib fragments,
+// their variants by values of string attributes such as "border";
+// nil attribs query whether any variant is supported.
+
+type Capabilities interface {
+    Supports(op string, attribs map[string]string) bool
+}
+
+// Engines that do not implement Capabilities are assumed
+// to support all operations
+
+func Supports(engine Engine, op string, attribs map[string]string) bool {
+    if c, ok := engine.(Capabilities); ok {
+        return c.Supports(op, attribs)
+    }
+    return true
+}
+
+//
+//    Buffers
+//
+
+// Storage that can hold several tensors of its dtype one at a time.
+// Engines that support shared storage implement BufferAllocator;
+// tensors created in the same buffer alias each other, so their
+// contents must never be needed at the same time. ItemSize returns
+// size in bytes of one item of dtype in engine storage.
+
+type Buffer interface {
+    Dtype() Dtype
+    Volume() int
+}
+
+type BufferAllocator interface {
+    NewBuffer(dtype Dtype, volume int) (Buffer, error)
+    ItemSize(dtype Dtype) int
+    NewTensorIn(buffer Buffer, shape []int) (Tensor, error)
+}
+
+//
+//    Errors
+//
+
+// Returned by engine methods for calls (or their variants) that
+// the engine does not implement; no output may be modified then.
+
+var ErrNotSupported = errors.New("operation not supported by engine")
+
 //
 //    Engine
 //
@@ -149,8 +270,12 @@ type Engine interface {
     Read(tensor Tensor, data interface{}) error
     Copy(input Tensor, output Tensor) error
     Unary(op UnaryOp, x Tensor, y Tensor) error
+    UnaryParam(op UnaryParamOp, x Tensor, y Tensor, alpha float32) error
     Binary(op BinaryOp, x Tensor, y Tensor, z Tensor) error
+    Accumulate(x []Tensor, y Tensor) error
+    Clamp(x Tensor, a Tensor, b Tensor, y Tensor) error
     Reduce(op ReduceOp, input Tensor, output Tensor) error
+    Moments(input Tensor, mean Tensor, variance Tensor) error
     Select(c Tensor, x Tensor, y Tensor, z Tensor) error
     Conv(
         transposed bool,
@@ -160,7 +285,8 @@ type Engine interface {
         output Tensor,
         padding []int,
         stride []int,
-        dilation []int) error
+        dilation []int,
+        border Border) error
     DepthwiseConv(
         transposed bool,
         input Tensor,
@@ -169,7 +295,8 @@ type Engine interface {
         output Tensor,
         padding []int,
         stride []int,
-        dilation []int) error
+        dilation []int,
+        border Border) error
     GroupedConv(
         transposed bool,
         input Tensor,
@@ -179,7 +306,8 @@ type Engine interface {
         padding []int,
         stride []int,
         dilation []int,
-        groups int) error
+        groups int,
+        border Border) error
     Pool(
         op PoolOp,
         transposed bool,
@@ -189,17 +317,78 @@ type Engine interface {
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) error
+        border Border) error
+    MaxPoolWithIndex(
+        input Tensor,
+        output Tensor,
+        index Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border Border) error
+    Sample(
+        transposed bool,
+        input Tensor,
+        index Tensor,
+        output Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border Border) error
+    BatchNorm(
+        input Tensor,
+        mean Tensor,
+        variance Tensor,
+        offset Tensor,
+        scale Tensor,
+        output Tensor,
+        epsilon float32) error
+    LocalNorm(
+        op LocalNormOp,
+        input Tensor,
+        output Tensor,
+        size []int,
+        alpha float32,
+        beta float32,
+        bias float32,
+        epsilon float32) error
+    Norm(op NormOp, input Tensor, output Tensor, axes []int, bias float32, epsilon float32) error
+    LinearQuantize(x Tensor, min Tensor, max Tensor, y Tensor, bits int) error
+    LogarithmicQuantize(x Tensor, max Tensor, y Tensor, bits int) error
     Matmul(trA bool, trB bool, a Tensor, b Tensor, c Tensor) error
     Linear(input Tensor, filter Tensor, bias Tensor, output Tensor) error
-    Softmax(input Tensor, output Tensor, axis int) error
-    ArgReduce(op ArgReduceOp, input Tensor, output Tensor, axis int) error
+    Softmax(input Tensor, output Tensor, axes []int) error
+    ArgReduce(op ArgReduceOp, input Tensor, output Tensor, axes []int) error
     Transpose(input Tensor, output Tensor, perm []int) error
     Concat(singular bool, x []Tensor, y Tensor, axis int) error
     Split(singular bool, x Tensor, y []Tensor, axis int) error
     PadConstant(input Tensor, output Tensor, padding []int, value interface{}) error
     PadReplicate(input Tensor, output Tensor, padding []int) error
+    PadReflect(input Tensor, output Tensor, padding []int, even bool) error
     Tile(input Tensor, output Tensor) error
     Slice(input Tensor, output Tensor, offset []int) error
+    RoiPool(op PoolOp, input Tensor, rois Tensor, batchIndex Tensor, output Tensor) error
+    RoiResample(
+        method UpsampleMethod,
+        input Tensor,
+        rois Tensor,
+        batchIndex Tensor,
+        output Tensor) error
+    RoiAlign(
+        op PoolOp,
+        method UpsampleMethod,
+        input Tensor,
+        rois Tensor,
+        batchIndex Tensor,
+        output Tensor,
+        samplingRate []int) error
+    MultilinearUpsample(
+        method UpsampleMethod,
+        border Border,
+        input Tensor,
+        output Tensor,
+        factor []int) error
 }
 
@@ -0,0 +1,1317 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package composite
+
+import (
+    "fragata/arhat/nnef/dnn/api"
+    "fragata/arhat/nnef/dnn/reference"
+)
+
+//
+//    Route
+//
+
+// numbers of calls executed by primary and fallback engine
+
+type Route struct {
+    Primary int
+    Fallback int
+}
+
+//
+//    Engine
+//
+
+// Engine routes each call to primary engine if primary reports support
+// of the operation variant (see api.Capabilities). Other calls and calls
+// declined by primary with api.ErrNotSupported are executed by fallback
+// engine; tensor data is moved between engines via Fill and Read as needed.
+// Primary engine must implement NewTensor, Fill and Read.
+
+type Engine struct {
+    primary api.Engine
+    fallback *reference.Engine
+    routing map[string]*Route
+}
+
+func NewEngine(primary api.Engine, fallback *reference.Engine) *Engine {
+    e := new(Engine)
+    e.primary = primary
+    e.fallback = fallback
+    e.routing = make(map[string]*Route)
+    return e
+}
+
+// Returns routing decisions made so far, keyed by engine method name
+
+func(e *Engine) Routing() map[string]Route {
+    result := make(map[string]Route)
+    for name, route := range e.routing {
+        result[name] = *route
+    }
+    return result
+}
+
+func(e *Engine) ResetRouting() {
+    e.routing = make(map[string]*Route)
+}
+
+// interface
+
+func(e *Engine) Supports(op string, attribs map[string]string) bool {
+    return api.Supports(e.primary, op, attribs) || e.fallback.Supports(op, attribs)
+}
+
+func(e *Engine) NewTensor(dtype api.Dtype, shape []int) (api.Tensor, error) {
+    primary, err := e.primary.NewTensor(dtype, shape)
+    if err != nil {
+        return nil, err
+    }
+    t := new(Tensor)
+    t.dtype = dtype
+    t.rank = len(shape)
+    t.volume = primary.Volume()
+    t.shape = cloneShape(shape)
+    t.primary = primary
+    t.valid = validPrimary
+    return t, nil
+}
+
+func(e *Engine) Fill(tensor api.Tensor, data interface{}) error {
+    t := tensor.(*Tensor)
+    err := e.primary.Fill(t.primary, data)
+    if err != nil {
+        return err
+    }
+    t.valid = validPrimary
+    return nil
+}
+
+func(e *Engine) Read(tensor api.Tensor, data interface{}) error {
+    t := tensor.(*Tensor)
+    if (t.valid & validPrimary) != 0 {
+        return e.primary.Read(t.primary, data)
+    }
+    return e.fallback.Read(t.fallback, data)
+}
+
+func(e *Engine) Copy(input api.Tensor, output api.Tensor) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Copy",
+        "copy",
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Copy(x.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.Copy(x.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) Unary(op api.UnaryOp, x api.Tensor, y api.Tensor) error {
+    tx := x.(*Tensor)
+    ty := y.(*Tensor)
+    return e.route(
+        "Unary",
+        unaryOpNames[op],
+        nil,
+        []*Tensor{tx},
+        []*Tensor{ty},
+        func() error {
+            return e.primary.Unary(op, tx.primary, ty.primary)
+        },
+        func() error {
+            return e.fallback.Unary(op, tx.fallback, ty.fallback)
+        })
+}
+
+func(e *Engine) UnaryParam(op api.UnaryParamOp, x api.Tensor, y api.Tensor, alpha float32) error {
+    tx := x.(*Tensor)
+    ty := y.(*Tensor)
+    return e.route(
+        "UnaryParam",
+        unaryParamOpNames[op],
+        nil,
+        []*Tensor{tx},
+        []*Tensor{ty},
+        func() error {
+            return e.primary.UnaryParam(op, tx.primary, ty.primary, alpha)
+        },
+        func() error {
+            return e.fallback.UnaryParam(op, tx.fallback, ty.fallback, alpha)
+        })
+}
+
+func(e *Engine) Binary(op api.BinaryOp, x api.Tensor, y api.Tensor, z api.Tensor) error {
+    tx := x.(*Tensor)
+    ty := y.(*Tensor)
+    tz := z.(*Tensor)
+    return e.route(
+        "Binary",
+        binaryOpNames[op],
+        nil,
+        []*Tensor{tx, ty},
+        []*Tensor{tz},
+        func() error {
+            return e.primary.Binary(op, tx.primary, ty.primary, tz.primary)
+        },
+        func() error {
+            return e.fallback.Binary(op, tx.fallback, ty.fallback, tz.fallback)
+        })
+}
+
+func(e *Engine) Accumulate(x []api.Tensor, y api.Tensor) error {
+    tx := unwrap(x)
+    ty := y.(*Tensor)
+    return e.route(
+        "Accumulate",
+        "add_n",
+        nil,
+        tx,
+        []*Tensor{ty},
+        func() error {
+            return e.primary.Accumulate(primaries(tx), ty.primary)
+        },
+        func() error {
+            return e.fallback.Accumulate(fallbacks(tx), ty.fallback)
+        })
+}
+
+func(e *Engine) Clamp(x api.Tensor, a api.Tensor, b api.Tensor, y api.Tensor) error {
+    tx := x.(*Tensor)
+    ta := a.(*Tensor)
+    tb := b.(*Tensor)
+    ty := y.(*Tensor)
+    return e.route(
+        "Clamp",
+        "clamp",
+        nil,
+        []*Tensor{tx, ta, tb},
+        []*Tensor{ty},
+        func() error {
+            return e.primary.Clamp(tx.primary, ta.primary, tb.primary, ty.primary)
+        },
+        func() error {
+            return e.fallback.Clamp(tx.fallback, ta.fallback, tb.fallback, ty.fallback)
+        })
+}
+
+func(e *Engine) Reduce(op api.ReduceOp, input api.Tensor, output api.Tensor) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Reduce",
+        reduceOpNames[op],
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Reduce(op, x.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.Reduce(op, x.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) Moments(input api.Tensor, mean api.Tensor, variance api.Tensor) error {
+    x := input.(*Tensor)
+    m := mean.(*Tensor)
+    v := variance.(*Tensor)
+    return e.route(
+        "Moments",
+        "moments",
+        nil,
+        []*Tensor{x},
+        []*Tensor{m, v},
+        func() error {
+            return e.primary.Moments(x.primary, m.primary, v.primary)
+        },
+        func() error {
+            return e.fallback.Moments(x.fallback, m.fallback, v.fallback)
+        })
+}
+
+func(e *Engine) Select(c api.Tensor, x api.Tensor, y api.Tensor, z api.Tensor) error {
+    tc := c.(*Tensor)
+    tx := x.(*Tensor)
+    ty := y.(*Tensor)
+    tz := z.(*Tensor)
+    return e.route(
+        "Select",
+        "select",
+        nil,
+        []*Tensor{tc, tx, ty},
+        []*Tensor{tz},
+        func() error {
+            return e.primary.Select(tc.primary, tx.primary, ty.primary, tz.primary)
+        },
+        func() error {
+            return e.fallback.Select(tc.fallback, tx.fallback, ty.fallback, tz.fallback)
+        })
+}
+
+func(e *Engine) Conv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    x := input.(*Tensor)
+    f := filter.(*Tensor)
+    b := bias.(*Tensor)
+    y := output.(*Tensor)
+    // transposed convolution writes result into input
+    reads, writes := []*Tensor{x, f, b}, []*Tensor{y}
+    if transposed {
+        reads, writes = []*Tensor{y, f, b}, []*Tensor{x}
+    }
+    return e.route(
+        "Conv",
+        convOpName(transposed),
+        borderAttribs(border),
+        reads,
+        writes,
+        func() error {
+            return e.primary.Conv(
+                transposed, 
+                x.primary, 
+                f.primary, 
+                b.primary, 
+                y.primary, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        },
+        func() error {
+            return e.fallback.Conv(
+                transposed, 
+                x.fallback, 
+                f.fallback, 
+                b.fallback, 
+                y.fallback, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        })
+}
+
+func(e *Engine) DepthwiseConv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    x := input.(*Tensor)
+    f := filter.(*Tensor)
+    b := bias.(*Tensor)
+    y := output.(*Tensor)
+    // transposed convolution writes result into input
+    reads, writes := []*Tensor{x, f, b}, []*Tensor{y}
+    if transposed {
+        reads, writes = []*Tensor{y, f, b}, []*Tensor{x}
+    }
+    return e.route(
+        "DepthwiseConv",
+        convOpName(transposed),
+        borderAttribs(border),
+        reads,
+        writes,
+        func() error {
+            return e.primary.DepthwiseConv(
+                transposed, 
+                x.primary, 
+                f.primary, 
+                b.primary, 
+                y.primary, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        },
+        func() error {
+            return e.fallback.DepthwiseConv(
+                transposed, 
+                x.fallback, 
+                f.fallback, 
+                b.fallback, 
+                y.fallback, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        })
+}
+
+func(e *Engine) GroupedConv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        groups int,
+        border api.Border) error {
+    x := input.(*Tensor)
+    f := filter.(*Tensor)
+    b := bias.(*Tensor)
+    y := output.(*Tensor)
+    // transposed convolution writes result into input
+    reads, writes := []*Tensor{x, f, b}, []*Tensor{y}
+    if transposed {
+        reads, writes = []*Tensor{y, f, b}, []*Tensor{x}
+    }
+    return e.route(
+        "GroupedConv",
+        convOpName(transposed),
+        borderAttribs(border),
+        reads,
+        writes,
+        func() error {
+            return e.primary.GroupedConv(
+                transposed, 
+                x.primary, 
+                f.primary, 
+                b.primary, 
+                y.primary, 
+                padding, 
+                stride, 
+                dilation, 
+                groups,
+                border)
+        },
+        func() error {
+            return e.fallback.GroupedConv(
+                transposed, 
+                x.fallback, 
+                f.fallback, 
+                b.fallback, 
+                y.fallback, 
+                padding, 
+                stride, 
+                dilation, 
+                groups,
+                border)
+        })
+}
+
+func(e *Engine) Pool(
+        op api.PoolOp,
+        transposed bool,
+        input api.Tensor,
+        output api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    // transposed pooling writes result into input
+    reads, writes := []*Tensor{x}, []*Tensor{y}
+    if transposed {
+        reads, writes = []*Tensor{y}, []*Tensor{x}
+    }
+    return e.route(
+        "Pool",
+        poolOpName(op, transposed),
+        borderAttribs(border),
+        reads,
+        writes,
+        func() error {
+            return e.primary.Pool(
+                op, 
+                transposed, 
+                x.primary, 
+                y.primary, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        },
+        func() error {
+            return e.fallback.Pool(
+                op, 
+                transposed, 
+                x.fallback, 
+                y.fallback, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        })
+}
+
+func(e *Engine) MaxPoolWithIndex(
+        input api.Tensor,
+        output api.Tensor,
+        index api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    // output is nil for argmax_pool
+    x := input.(*Tensor)
+    i := index.(*Tensor)
+    var y *Tensor
+    writes := []*Tensor{i}
+    if output != nil {
+        y = output.(*Tensor)
+        writes = append(writes, y)
+    }
+    return e.route(
+        "MaxPoolWithIndex",
+        maxPoolWithIndexOpName(y),
+        borderAttribs(border),
+        []*Tensor{x},
+        writes,
+        func() error {
+            return e.primary.MaxPoolWithIndex(
+                x.primary, 
+                primaryOf(y), 
+                i.primary, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        },
+        func() error {
+            return e.fallback.MaxPoolWithIndex(
+                x.fallback, 
+                fallbackOf(y), 
+                i.fallback, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        })
+}
+
+func(e *Engine) Sample(
+        transposed bool,
+        input api.Tensor,
+        index api.Tensor,
+        output api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    x := input.(*Tensor)
+    i := index.(*Tensor)
+    y := output.(*Tensor)
+    // desample writes result into input
+    reads, writes := []*Tensor{x, i}, []*Tensor{y}
+    if transposed {
+        reads, writes = []*Tensor{y, i}, []*Tensor{x}
+    }
+    return e.route(
+        "Sample",
+        sampleOpName(transposed),
+        borderAttribs(border),
+        reads,
+        writes,
+        func() error {
+            return e.primary.Sample(
+                transposed, 
+                x.primary, 
+                i.primary, 
+                y.primary, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        },
+        func() error {
+            return e.fallback.Sample(
+                transposed, 
+                x.fallback, 
+                i.fallback, 
+                y.fallback, 
+                size, 
+                padding, 
+                stride, 
+                dilation, 
+                border)
+        })
+}
+
+func(e *Engine) BatchNorm(
+        input api.Tensor,
+        mean api.Tensor,
+        variance api.Tensor,
+        offset api.Tensor,
+        scale api.Tensor,
+        output api.Tensor,
+        epsilon float32) error {
+    x := input.(*Tensor)
+    m := mean.(*Tensor)
+    v := variance.(*Tensor)
+    o := offset.(*Tensor)
+    s := scale.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "BatchNorm",
+        "batch_normalization",
+        nil,
+        []*Tensor{x, m, v, o, s},
+        []*Tensor{y},
+        func() error {
+            return e.primary.BatchNorm(
+                x.primary, 
+                m.primary, 
+                v.primary, 
+                o.primary, 
+                s.primary, 
+                y.primary, 
+                epsilon)
+        },
+        func() error {
+            return e.fallback.BatchNorm(
+                x.fallback, 
+                m.fallback, 
+                v.fallback, 
+                o.fallback, 
+                s.fallback, 
+                y.fallback, 
+                epsilon)
+        })
+}
+
+func(e *Engine) LocalNorm(
+        op api.LocalNormOp,
+        input api.Tensor,
+        output api.Tensor,
+        size []int,
+        alpha float32,
+        beta float32,
+        bias float32,
+        epsilon float32) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "LocalNorm",
+        localNormOpNames[op],
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.LocalNorm(op, x.primary, y.primary, size, alpha, beta, bias, epsilon)
+        },
+        func() error {
+            return e.fallback.LocalNorm(op, x.fallback, y.fallback, size, alpha, beta, bias, epsilon)
+        })
+}
+
+func(e *Engine) Norm(
+        op api.NormOp, 
+        input api.Tensor, 
+        output api.Tensor, 
+        axes []int, 
+        bias float32, 
+        epsilon float32) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Norm",
+        normOpNames[op],
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Norm(op, x.primary, y.primary, axes, bias, epsilon)
+        },
+        func() error {
+            return e.fallback.Norm(op, x.fallback, y.fallback, axes, bias, epsilon)
+        })
+}
+
+func(e *Engine) LinearQuantize(
+        x api.Tensor, 
+        min api.Tensor, 
+        max api.Tensor, 
+        y api.Tensor, 
+        bits int) error {
+    tx := x.(*Tensor)
+    tmin := min.(*Tensor)
+    tmax := max.(*Tensor)
+    ty := y.(*Tensor)
+    return e.route(
+        "LinearQuantize",
+        "linear_quantize",
+        nil,
+        []*Tensor{tx, tmin, tmax},
+        []*Tensor{ty},
+        func() error {
+            return e.primary.LinearQuantize(tx.primary, tmin.primary, tmax.primary, ty.primary, bits)
+        },
+        func() error {
+            return e.fallback.LinearQuantize(tx.fallback, tmin.fallback, tmax.fallback, ty.fallback, bits)
+        })
+}
+
+func(e *Engine) LogarithmicQuantize(x api.Tensor, max api.Tensor, y api.Tensor, bits int) error {
+    tx := x.(*Tensor)
+    tmax := max.(*Tensor)
+    ty := y.(*Tensor)
+    return e.route(
+        "LogarithmicQuantize",
+        "logarithmic_quantize",
+        nil,
+        []*Tensor{tx, tmax},
+        []*Tensor{ty},
+        func() error {
+            return e.primary.LogarithmicQuantize(tx.primary, tmax.primary, ty.primary, bits)
+        },
+        func() error {
+            return e.fallback.LogarithmicQuantize(tx.fallback, tmax.fallback, ty.fallback, bits)
+        })
+}
+
+func(e *Engine) Matmul(trA bool, trB bool, a api.Tensor, b api.Tensor, c api.Tensor) error {
+    ta := a.(*Tensor)
+    tb := b.(*Tensor)
+    tc := c.(*Tensor)
+    return e.route(
+        "Matmul",
+        "matmul",
+        nil,
+        []*Tensor{ta, tb},
+        []*Tensor{tc},
+        func() error {
+            return e.primary.Matmul(trA, trB, ta.primary, tb.primary, tc.primary)
+        },
+        func() error {
+            return e.fallback.Matmul(trA, trB, ta.fallback, tb.fallback, tc.fallback)
+        })
+}
+
+func(e *Engine) Linear(
+        input api.Tensor, 
+        filter api.Tensor, 
+        bias api.Tensor, 
+        output api.Tensor) error {
+    x := input.(*Tensor)
+    f := filter.(*Tensor)
+    b := bias.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Linear",
+        "linear",
+        nil,
+        []*Tensor{x, f, b},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Linear(x.primary, f.primary, b.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.Linear(x.fallback, f.fallback, b.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) Softmax(input api.Tensor, output api.Tensor, axes []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Softmax",
+        "softmax",
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Softmax(x.primary, y.primary, axes)
+        },
+        func() error {
+            return e.fallback.Softmax(x.fallback, y.fallback, axes)
+        })
+}
+
+func(e *Engine) ArgReduce(
+        op api.ArgReduceOp, 
+        input api.Tensor, 
+        output api.Tensor, 
+        axes []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "ArgReduce",
+        argReduceOpNames[op],
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.ArgReduce(op, x.primary, y.primary, axes)
+        },
+        func() error {
+            return e.fallback.ArgReduce(op, x.fallback, y.fallback, axes)
+        })
+}
+
+func(e *Engine) Transpose(input api.Tensor, output api.Tensor, perm []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Transpose",
+        "transpose",
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Transpose(x.primary, y.primary, perm)
+        },
+        func() error {
+            return e.fallback.Transpose(x.fallback, y.fallback, perm)
+        })
+}
+
+func(e *Engine) Concat(singular bool, x []api.Tensor, y api.Tensor, axis int) error {
+    tx := unwrap(x)
+    ty := y.(*Tensor)
+    return e.route(
+        "Concat",
+        concatOpName(singular),
+        nil,
+        tx,
+        []*Tensor{ty},
+        func() error {
+            return e.primary.Concat(singular, primaries(tx), ty.primary, axis)
+        },
+        func() error {
+            return e.fallback.Concat(singular, fallbacks(tx), ty.fallback, axis)
+        })
+}
+
+func(e *Engine) Split(singular bool, x api.Tensor, y []api.Tensor, axis int) error {
+    tx := x.(*Tensor)
+    ty := unwrap(y)
+    return e.route(
+        "Split",
+        splitOpName(singular),
+        nil,
+        []*Tensor{tx},
+        ty,
+        func() error {
+            return e.primary.Split(singular, tx.primary, primaries(ty), axis)
+        },
+        func() error {
+            return e.fallback.Split(singular, tx.fallback, fallbacks(ty), axis)
+        })
+}
+
+func(e *Engine) PadConstant(
+        input api.Tensor, 
+        output api.Tensor, 
+        padding []int, 
+        value interface{}) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "PadConstant",
+        "pad",
+        map[string]string{"border": "constant"},
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.PadConstant(x.primary, y.primary, padding, value)
+        },
+        func() error {
+            return e.fallback.PadConstant(x.fallback, y.fallback, padding, value)
+        })
+}
+
+func(e *Engine) PadReplicate(input api.Tensor, output api.Tensor, padding []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "PadReplicate",
+        "pad",
+        map[string]string{"border": "replicate"},
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.PadReplicate(x.primary, y.primary, padding)
+        },
+        func() error {
+            return e.fallback.PadReplicate(x.fallback, y.fallback, padding)
+        })
+}
+
+func(e *Engine) PadReflect(input api.Tensor, output api.Tensor, padding []int, even bool) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "PadReflect",
+        "pad",
+        map[string]string{"border": reflectBorderName(even)},
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.PadReflect(x.primary, y.primary, padding, even)
+        },
+        func() error {
+            return e.fallback.PadReflect(x.fallback, y.fallback, padding, even)
+        })
+}
+
+func(e *Engine) Tile(input api.Tensor, output api.Tensor) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Tile",
+        "tile",
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Tile(x.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.Tile(x.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) Slice(input api.Tensor, output api.Tensor, offset []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "Slice",
+        "slice",
+        nil,
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.Slice(x.primary, y.primary, offset)
+        },
+        func() error {
+            return e.fallback.Slice(x.fallback, y.fallback, offset)
+        })
+}
+
+func(e *Engine) RoiPool(
+        op api.PoolOp,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor) error {
+    x := input.(*Tensor)
+    r := rois.(*Tensor)
+    b := batchIndex.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "RoiPool",
+        roiPoolOpName(op),
+        nil,
+        []*Tensor{x, r, b},
+        []*Tensor{y},
+        func() error {
+            return e.primary.RoiPool(op, x.primary, r.primary, b.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.RoiPool(op, x.fallback, r.fallback, b.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) RoiResample(
+        method api.UpsampleMethod,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor) error {
+    x := input.(*Tensor)
+    r := rois.(*Tensor)
+    b := batchIndex.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "RoiResample",
+        "roi_resample",
+        map[string]string{"method": methodName(method)},
+        []*Tensor{x, r, b},
+        []*Tensor{y},
+        func() error {
+            return e.primary.RoiResample(method, x.primary, r.primary, b.primary, y.primary)
+        },
+        func() error {
+            return e.fallback.RoiResample(method, x.fallback, r.fallback, b.fallback, y.fallback)
+        })
+}
+
+func(e *Engine) RoiAlign(
+        op api.PoolOp,
+        method api.UpsampleMethod,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor,
+        samplingRate []int) error {
+    x := input.(*Tensor)
+    r := rois.(*Tensor)
+    b := batchIndex.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "RoiAlign",
+        roiAlignOpName(op),
+        map[string]string{"resize_method": methodName(method)},
+        []*Tensor{x, r, b},
+        []*Tensor{y},
+        func() error {
+            return e.primary.RoiAlign(
+                op, 
+                method, 
+                x.primary, 
+                r.primary, 
+                b.primary, 
+                y.primary, 
+                samplingRate)
+        },
+        func() error {
+            return e.fallback.RoiAlign(
+                op, 
+                method, 
+                x.fallback, 
+                r.fallback, 
+                b.fallback, 
+                y.fallback, 
+                samplingRate)
+        })
+}
+
+func(e *Engine) MultilinearUpsample(
+        method api.UpsampleMethod,
+        border api.Border,
+        input api.Tensor,
+        output api.Tensor,
+        factor []int) error {
+    x := input.(*Tensor)
+    y := output.(*Tensor)
+    return e.route(
+        "MultilinearUpsample",
+        "multilinear_upsample",
+        map[string]string{"method": methodName(method), "border": borderName(border)},
+        []*Tensor{x},
+        []*Tensor{y},
+        func() error {
+            return e.primary.MultilinearUpsample(method, border, x.primary, y.primary, factor)
+        },
+        func() error {
+            return e.fallback.MultilinearUpsample(method, border, x.fallback, y.fallback, factor)
+        })
+}
+
+// implementation
+
+// Route is decided before any data is moved: primary engine is tried
+// only if it reports support of the operation variant. Transposed
+// operations read their output and write their input tensors.
+
+func(e *Engine) route(
+        name string,
+        op string,
+        attribs map[string]string,
+        reads []*Tensor,
+        writes []*Tensor,
+        onPrimary func() error,
+        onFallback func() error) error {
+    route, ok := e.routing[name]
+    if !ok {
+        route = new(Route)
+        e.routing[name] = route
+    }
+    if api.Supports(e.primary, op, attribs) {
+        for _, t := range reads {
+            err := e.toPrimary(t)
+            if err != nil {
+                return err
+            }
+        }
+        err := onPrimary()
+        if err != api.ErrNotSupported {
+            route.Primary++
+            if err != nil {
+                return err
+            }
+            for _, t := range writes {
+                t.valid = validPrimary
+            }
+            return nil
+        }
+    }
+    route.Fallback++
+    for _, t := range reads {
+        err := e.toFallback(t)
+        if err != nil {
+            return err
+        }
+    }
+    for _, t := range writes {
+        err := e.allocFallback(t)
+        if err != nil {
+            return err
+        }
+    }
+    err := onFallback()
+    if err != nil {
+        return err
+    }
+    for _, t := range writes {
+        t.valid = validFallback
+    }
+    return nil
+}
+
+func(e *Engine) toPrimary(t *Tensor) error {
+    if (t.valid & validPrimary) != 0 {
+        return nil
+    }
+    data := makeData(t.dtype, t.volume)
+    err := e.fallback.Read(t.fallback, data)
+    if err != nil {
+        return err
+    }
+    err = e.primary.Fill(t.primary, data)
+    if err != nil {
+        return err
+    }
+    t.valid |= validPrimary
+    return nil
+}
+
+func(e *Engine) toFallback(t *Tensor) error {
+    if (t.valid & validFallback) != 0 {
+        return nil
+    }
+    err := e.allocFallback(t)
+    if err != nil {
+        return err
+    }
+    data := makeData(t.dtype, t.volume)
+    err = e.primary.Read(t.primary, data)
+    if err != nil {
+        return err
+    }
+    err = e.fallback.Fill(t.fallback, data)
+    if err != nil {
+        return err
+    }
+    t.valid |= validFallback
+    return nil
+}
+
+func(e *Engine) allocFallback(t *Tensor) error {
+    if t.fallback != nil {
+        return nil
+    }
+    fallback, err := e.fallback.NewTensor(t.dtype, t.shape)
+    if err != nil {
+        return err
+    }
+    t.fallback = fallback
+    return nil
+}
+
+// names of NNEF stdlib operations queried for engine methods
+
+var unaryOpNames = [...]string{
+    api.OpNeg: "neg",
+    api.OpNot: "not",
+    api.OpAbs: "abs",
+    api.OpSign: "sign",
+    api.OpExp: "exp",
+    api.OpLog: "log",
+    api.OpLog2: "log2",
+    api.OpSin: "sin",
+    api.OpCos: "cos",
+    api.OpRound: "round",
+    api.OpFloor: "floor",
+    api.OpCeil: "ceil",
+    api.OpSqrt: "sqrt",
+    api.OpSqr: "sqr",
+    api.OpRsqrt: "rsqrt",
+    api.OpRsqr: "rsqr",
+    api.OpRcp: "rcp",
+    api.OpCopy: "copy",
+    api.OpSigmoid: "sigmoid",
+    api.OpTanh: "tanh",
+    api.OpRelu: "relu",
+    api.OpElu: "elu",
+    api.OpSoftplus: "softplus",
+}
+
+var unaryParamOpNames = [...]string{
+    api.OpLeakyRelu: "leaky_relu",
+    api.OpSoftabs: "softabs",
+}
+
+var binaryOpNames = [...]string{
+    api.OpAdd: "add",
+    api.OpSub: "sub",
+    api.OpMul: "mul",
+    api.OpDiv: "div",
+    api.OpPow: "pow",
+    api.OpMin: "min",
+    api.OpMax: "max",
+    api.OpAnd: "and",
+    api.OpOr: "or",
+    api.OpLt: "lt",
+    api.OpGt: "gt",
+    api.OpLe: "le",
+    api.OpGe: "ge",
+    api.OpEq: "eq",
+    api.OpNe: "ne",
+    api.OpPrelu: "prelu",
+}
+
+var reduceOpNames = [...]string{
+    api.OpSumReduce: "sum_reduce",
+    api.OpMeanReduce: "mean_reduce",
+    api.OpMinReduce: "min_reduce",
+    api.OpMaxReduce: "max_reduce",
+    api.OpAnyReduce: "any_reduce",
+    api.OpAllReduce: "all_reduce",
+}
+
+var argReduceOpNames = [...]string{
+    api.OpArgminReduce: "argmin_reduce",
+    api.OpArgmaxReduce: "argmax_reduce",
+}
+
+var localNormOpNames = [...]string{
+    api.OpLocalResponseNorm: "local_response_normalization",
+    api.OpLocalMeanNorm: "local_mean_normalization",
+    api.OpLocalVarianceNorm: "local_variance_normalization",
+    api.OpLocalContrastNorm: "local_contrast_normalization",
+}
+
+var normOpNames = [...]string{
+    api.OpL1Norm: "l1_normalization",
+    api.OpL2Norm: "l2_normalization",
+}
+
+var borderNames = [...]string{
+    api.BorderConstant: "constant",
+    api.BorderIgnore: "ignore",
+    api.BorderReplicate: "replicate",
+    api.BorderReflect: "reflect",
+    api.BorderReflectEven: "reflect-even",
+}
+
+var methodNames = [...]string{
+    api.UpsampleSymmetric: "symmetric",
+    api.UpsampleAsymmetric: "asymmetric",
+    api.UpsampleAligned: "aligned",
+}
+
+func convOpName(transposed bool) string {
+    if transposed {
+        return "deconv"
+    }
+    return "conv"
+}
+
+func poolOpName(op api.PoolOp, transposed bool) string {
+    switch {
+    case transposed:
+        return "debox"
+    case op == api.OpSumPool:
+        return "box"
+    case op == api.OpAvgPool:
+        return "avg_pool"
+    default:
+        return "max_pool"
+    }
+}
+
+func maxPoolWithIndexOpName(output *Tensor) string {
+    if output == nil {
+        return "argmax_pool"
+    }
+    return "max_pool_with_index"
+}
+
+func sampleOpName(transposed bool) string {
+    if transposed {
+        return "desample"
+    }
+    return "sample"
+}
+
+func concatOpName(singular bool) string {
+    if singular {
+        return "stack"
+    }
+    return "concat"
+}
+
+func splitOpName(singular bool) string {
+    if singular {
+        return "unstack"
+    }
+    return "split"
+}
+
+func roiPoolOpName(op api.PoolOp) string {
+    if op == api.OpMaxPool {
+        return "max_roi_pool"
+    }
+    return "avg_roi_pool"
+}
+
+func roiAlignOpName(op api.PoolOp) string {
+    if op == api.OpMaxPool {
+        return "max_roi_align"
+    }
+    return "avg_roi_align"
+}
+
+func reflectBorderName(even bool) string {
+    if even {
+        return "reflect-even"
+    }
+    return "reflect"
+}
+
+func borderName(border api.Border) string {
+    return borderNames[border]
+}
+
+func methodName(method api.UpsampleMethod) string {
+    return methodNames[method]
+}
+
+func borderAttribs(border api.Border) map[string]string {
+    return map[string]string{"border": borderName(border)}
+}
@@ -0,0 +1,401 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package composite
+
+import (
+    "testing"
+    "fragata/arhat/nnef/dnn/api"
+    "fragata/arhat/nnef/dnn/reference"
+)
+
+//
+//    Test primary engine
+//
+
+// Reference engine that declines convolution, pooling and sampling
+// calls with api.ErrNotSupported and counts calls it receives.
+// If capable is false, it reports no support for declined operations.
+
+type decliningEngine struct {
+    *reference.Engine
+    capable bool
+    calls int
+    fills int
+}
+
+var declinedOps = map[string]bool{
+    "conv": true,
+    "deconv": true,
+    "box": true,
+    "debox": true,
+    "max_pool": true,
+    "argmax_pool": true,
+    "max_pool_with_index": true,
+    "sample": true,
+    "desample": true,
+}
+
+func newDecliningEngine(capable bool) *decliningEngine {
+    return &decliningEngine{Engine: reference.NewEngine(1), capable: capable}
+}
+
+func(e *decliningEngine) Supports(op string, attribs map[string]string) bool {
+    if !e.capable && declinedOps[op] {
+        return false
+    }
+    return e.Engine.Supports(op, attribs)
+}
+
+func(e *decliningEngine) Fill(tensor api.Tensor, data interface{}) error {
+    e.fills++
+    return e.Engine.Fill(tensor, data)
+}
+
+func(e *decliningEngine) Conv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+func(e *decliningEngine) DepthwiseConv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+func(e *decliningEngine) GroupedConv(
+        transposed bool,
+        input api.Tensor,
+        filter api.Tensor,
+        bias api.Tensor,
+        output api.Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        groups int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+func(e *decliningEngine) Pool(
+        op api.PoolOp,
+        transposed bool,
+        input api.Tensor,
+        output api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+func(e *decliningEngine) MaxPoolWithIndex(
+        input api.Tensor,
+        output api.Tensor,
+        index api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+func(e *decliningEngine) Sample(
+        transposed bool,
+        input api.Tensor,
+        index api.Tensor,
+        output api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    e.calls++
+    return api.ErrNotSupported
+}
+
+//
+//    Tests
+//
+
+func TestDeconvFallback(t *testing.T) {
+    e := NewEngine(newDecliningEngine(true), reference.NewEngine(1))
+    x := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, make([]float32, 4))
+    f := newTensor(t, e, api.DtypeFloat, []int{1, 1, 1}, []float32{2})
+    b := newTensor(t, e, api.DtypeFloat, []int{1, 1}, []float32{0})
+    y := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, []float32{1, 2, 3, 4})
+    err := e.Conv(true, x, f, b, y, []int{0}, []int{1}, []int{1}, api.BorderConstant)
+    if err != nil {
+        t.Fatal(err)
+    }
+    checkEqual(t, "deconv", readFloat(t, e, x), []float32{2, 4, 6, 8})
+}
+
+// Transposed operations write their result into input tensor;
+// fallback results must match reference engine run directly.
+
+func TestTransposedFallback(t *testing.T) {
+    type transposedCase struct {
+        name string
+        run func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error
+        input []int
+        output []int
+        aux [][]int
+        auxInt bool
+    }
+    cases := []transposedCase{
+        {
+            name: "deconv",
+            run: func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error {
+                return e.Conv(
+                    true, x, aux[0], aux[1], y,
+                    []int{1, 1}, []int{2, 2}, []int{1, 1}, api.BorderConstant)
+            },
+            input: []int{1, 3, 6, 6},
+            output: []int{1, 2, 3, 3},
+            aux: [][]int{{2, 3, 3, 3}, {1, 3}},
+        },
+        {
+            name: "depthwise deconv",
+            run: func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error {
+                return e.DepthwiseConv(
+                    true, x, aux[0], aux[1], y,
+                    []int{1, 1}, []int{1, 1}, []int{1, 1}, api.BorderConstant)
+            },
+            input: []int{1, 2, 4, 4},
+            output: []int{1, 4, 4, 4},
+            aux: [][]int{{4, 1, 3, 3}, {1, 2}},
+        },
+        {
+            name: "grouped deconv",
+            run: func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error {
+                return e.GroupedConv(
+                    true, x, aux[0], aux[1], y,
+                    []int{0, 0}, []int{1, 1}, []int{1, 1}, 2, api.BorderConstant)
+            },
+            input: []int{1, 4, 5, 5},
+            output: []int{1, 4, 4, 4},
+            aux: [][]int{{4, 2, 2, 2}, {1, 4}},
+        },
+        {
+            name: "debox",
+            run: func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error {
+                return e.Pool(
+                    api.OpSumPool, true, x, y,
+                    []int{1, 1, 2, 2}, []int{0, 0, 0, 0}, []int{1, 1, 2, 2}, []int{1, 1, 1, 1},
+                    api.BorderConstant)
+            },
+            input: []int{1, 2, 4, 4},
+            output: []int{1, 2, 2, 2},
+        },
+        {
+            name: "desample",
+            run: func(e api.Engine, x api.Tensor, y api.Tensor, aux []api.Tensor) error {
+                return e.Sample(
+                    true, x, aux[0], y,
+                    []int{1, 1, 2, 2}, []int{0, 0, 0, 0}, []int{1, 1, 2, 2}, []int{1, 1, 1, 1},
+                    api.BorderConstant)
+            },
+            input: []int{1, 2, 4, 4},
+            output: []int{1, 2, 2, 2},
+            aux: [][]int{{1, 2, 2, 2}},
+            auxInt: true,
+        },
+    }
+    for _, c := range cases {
+        var results [2][]float32
+        for k := 0; k < 2; k++ {
+            var e api.Engine
+            if k == 0 {
+                e = NewEngine(newDecliningEngine(true), reference.NewEngine(1))
+            } else {
+                e = reference.NewEngine(1)
+            }
+            x := newTensor(t, e, api.DtypeFloat, c.input, make([]float32, volumeOf(c.input)))
+            y := newTensor(t, e, api.DtypeFloat, c.output, testData(volumeOf(c.output), 1))
+            aux := make([]api.Tensor, len(c.aux))
+            for i, shape := range c.aux {
+                if c.auxInt {
+                    data := make([]int, volumeOf(shape))
+                    for j := range data {
+                        data[j] = j % 4
+                    }
+                    aux[i] = newTensor(t, e, api.DtypeInt, shape, data)
+                } else {
+                    aux[i] = newTensor(t, e, api.DtypeFloat, shape, testData(volumeOf(shape), i+2))
+                }
+            }
+            err := c.run(e, x, y, aux)
+            if err != nil {
+                t.Fatalf("%s: %v", c.name, err)
+            }
+            results[k] = readFloat(t, e, x)
+        }
+        checkEqual(t, c.name, results[0], results[1])
+        if isZero(results[0]) {
+            t.Errorf("%s: fallback left result zero", c.name)
+        }
+    }
+}
+
+func TestArgmaxPoolFallback(t *testing.T) {
+    e := NewEngine(newDecliningEngine(true), reference.NewEngine(1))
+    x := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, []float32{1, 3, 4, 2})
+    i := newTensor(t, e, api.DtypeInt, []int{1, 1, 2}, make([]int, 2))
+    err :=
+        e.MaxPoolWithIndex(
+            x, nil, i,
+            []int{1, 1, 2}, []int{0, 0, 0}, []int{1, 1, 2}, []int{1, 1, 1},
+            api.BorderConstant)
+    if err != nil {
+        t.Fatal(err)
+    }
+    index := make([]int, 2)
+    err = e.Read(i, index)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if index[0] != 1 || index[1] != 0 {
+        t.Errorf("argmax_pool: got %v, want [1 0]", index)
+    }
+}
+
+// Primary that reports no support must not be called
+// and must not receive input data
+
+func TestRouteByCapabilities(t *testing.T) {
+    primary := newDecliningEngine(false)
+    e := NewEngine(primary, reference.NewEngine(1))
+    x := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, make([]float32, 4))
+    f := newTensor(t, e, api.DtypeFloat, []int{1, 1, 1}, []float32{2})
+    b := newTensor(t, e, api.DtypeFloat, []int{1, 1}, []float32{0})
+    y := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, []float32{1, 2, 3, 4})
+    z := newTensor(t, e, api.DtypeFloat, []int{1, 1, 4}, make([]float32, 4))
+    err := e.Conv(false, y, f, b, x, []int{0}, []int{1}, []int{1}, api.BorderConstant)
+    if err != nil {
+        t.Fatal(err)
+    }
+    fills := primary.fills
+    err = e.Conv(false, x, f, b, z, []int{0}, []int{1}, []int{1}, api.BorderConstant)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if primary.calls != 0 {
+        t.Errorf("primary called %d times", primary.calls)
+    }
+    if primary.fills != fills {
+        t.Errorf("data moved to primary %d times", primary.fills-fills)
+    }
+    checkEqual(t, "conv", readFloat(t, e, z), []float32{4, 8, 12, 16})
+    // unary op is supported by primary, input moves back
+    err = e.Unary(api.OpNeg, z, x)
+    if err != nil {
+        t.Fatal(err)
+    }
+    checkEqual(t, "neg", readFloat(t, e, x), []float32{-4, -8, -12, -16})
+    routing := e.Routing()
+    if routing["Conv"] != (Route{Primary: 0, Fallback: 2}) {
+        t.Errorf("Conv routing: got %v", routing["Conv"])
+    }
+    if routing["Unary"] != (Route{Primary: 1, Fallback: 0}) {
+        t.Errorf("Unary routing: got %v", routing["Unary"])
+    }
+}
+
+//
+//    Utility functions
+//
+
+func newTensor(t *testing.T, e api.Engine, dtype api.Dtype, shape []int, data interface{}) api.Tensor {
+    tensor, err := e.NewTensor(dtype, shape)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = e.Fill(tensor, data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tensor
+}
+
+func readFloat(t *testing.T, e api.Engine, tensor api.Tensor) []float32 {
+    data := make([]float32, tensor.Volume())
+    err := e.Read(tensor, data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return data
+}
+
+func testData(n int, seed int) []float32 {
+    data := make([]float32, n)
+    for i := range data {
+        data[i] = float32((i * 7 + seed * 3) % 11) - 5.0
+    }
+    return data
+}
+
+func volumeOf(shape []int) int {
+    volume := 1
+    for _, dim := range shape {
+        volume *= dim
+    }
+    return volume
+}
+
+func isZero(x []float32) bool {
+    for _, v := range x {
+        if v != 0.0 {
+            return false
+        }
+    }
+    return true
+}
+
+func checkEqual(t *testing.T, name string, got []float32, want []float32) {
+    if len(got) != len(want) {
+        t.Errorf("%s: got %d items, want %d", name, len(got), len(want))
+        return
+    }
+    for i := range got {
+        if got[i] != want[i] {
+            t.Errorf("%s: item %d: got %g, want %g", name, i, got[i], want[i])
+            return
+        }
+    }
+}
@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package composite
+
+import "fragata/arhat/nnef/dnn/api"
+
+//
+//    Tensor
+//
+
+// Tensor is always allocated by primary engine; fallback copy 
+// is allocated on first use. Flags in valid mark which copies 
+// hold current data.
+
+type Tensor struct {
+    dtype api.Dtype
+    rank int
+    volume int
+    shape []int
+    primary api.Tensor
+    fallback api.Tensor
+    valid int
+}
+
+const (
+    validPrimary = 1 << iota
+    validFallback
+)
+
+func(t *Tensor) Dtype() api.Dtype {
+    return t.dtype
+}
+
+func(t *Tensor) Rank() int {
+    return t.rank
+}
+
+func(t *Tensor) Volume() int {
+    return t.volume
+}
+
+func(t *Tensor) Shape() []int {
+    return t.shape
+}
+
+// implementation
+
+func makeData(dtype api.Dtype, volume int) interface{} {
+    switch dtype {
+    case api.DtypeBool:
+        return make([]bool, volume)
+    case api.DtypeInt:
+        return make([]int, volume)
+    case api.DtypeFloat:
+        return make([]float32, volume)
+    default:
+        return nil
+    }
+}
+
+func cloneShape(shape []int) []int {
+    if len(shape) == 0 {
+        return nil
+    }
+    result := make([]int, len(shape))
+    copy(result, shape)
+    return result
+}
+
+func unwrap(x []api.Tensor) []*Tensor {
+    result := make([]*Tensor, len(x))
+    for i, t := range x {
+        result[i] = t.(*Tensor)
+    }
+    return result
+}
+
+func primaries(x []*Tensor) []api.Tensor {
+    result := make([]api.Tensor, len(x))
+    for i, t := range x {
+        result[i] = t.primary
+    }
+    return result
+}
+
+func fallbacks(x []*Tensor) []api.Tensor {
+    result := make([]api.Tensor, len(x))
+    for i, t := range x {
+        result[i] = t.fallback
+    }
+    return result
+}
+
+
+// optional tensors are passed as nil
+
+func primaryOf(t *Tensor) api.Tensor {
+    if t == nil {
+        return nil
+    }
+    return t.primary
+}
+
+func fallbackOf(t *Tensor) api.Tensor {
+    if t == nil {
+        return nil
+    }
+    return t.fallback
+}
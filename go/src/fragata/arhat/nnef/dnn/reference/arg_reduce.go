@@ -26,15 +26,15 @@ import "fragata/arhat/nnef/dnn/api"
 
 // interface
 
-func ArgReduce(op api.ArgReduceOp, input *Tensor, output *Tensor, axis int) error {
+func ArgReduce(op api.ArgReduceOp, input *Tensor, output *Tensor, axes []int) error {
     kernel := getArgReduceKernel(op)
-    argReduceLoopFloat(input, output, axis, kernel)
+    argReduceLoopFloat(input, output, axes, kernel)
     return nil
 }
 
 // implementation
 
-type argReduceKernel func(n int, ax interface{}, px int, dx int) int
+type argReduceKernel func(ax interface{}, px int, offsets []int) int
 
 var argReduceKernels = [...]argReduceKernel{
     api.OpArgminReduce: argReduceArgminFloat,
@@ -45,28 +45,30 @@ func getArgReduceKernel(op api.ArgReduceOp) argReduceKernel {
     return argReduceKernels[op]
 }
 
-func argReduceLoopFloat(input *Tensor, output *Tensor, axis int, kernel argReduceKernel) {
-    batch := volumeOf(input.shape[:axis])
-    channels := input.shape[axis]
-    size := volumeOf(input.shape[axis+1:])
-    volume := channels * size
+// Output holds flattened index within reduced sub-volume.
+
+func argReduceLoopFloat(input *Tensor, output *Tensor, axes []int, kernel argReduceKernel) {
     x := input.FloatData()
     y := output.IntData()
-    for i := 0; i < batch; i++ {
-        for j := 0; j < size; j++ {
-            y[i*size+j] = kernel(channels, x, i*volume+j, size)
-        }
+    outerShape, offsets := reducedLayout(input.shape, axes)
+    k := 0
+    var loop NdLoop
+    for loop.Start(outerShape); loop.Test(); loop.Next() {
+        px := NdOffset(input.shape, loop.Index())
+        y[k] = kernel(x, px, offsets)
+        k++
     }
 }
 
 // kernels
 
-func argReduceArgminFloat(n int, ax interface{}, px int, dx int) int {
+func argReduceArgminFloat(ax interface{}, px int, offsets []int) int {
     x := ax.([]float32)
+    n := len(offsets)
     idx := 0
-    val := x[0]
+    val := x[px+offsets[0]]
     for i := 1; i < n; i++ {
-        xi := x[i*dx+px]
+        xi := x[px+offsets[i]]
         if xi < val {
             val = xi
             idx = i
@@ -75,12 +77,13 @@ func argReduceArgminFloat(n int, ax interface{}, px int, dx int) int {
     return idx
 }
 
-func argReduceArgmaxFloat(n int, ax interface{}, px int, dx int) int {
+func argReduceArgmaxFloat(ax interface{}, px int, offsets []int) int {
     x := ax.([]float32)
+    n := len(offsets)
     idx := 0
-    val := x[0]
+    val := x[px+offsets[0]]
     for i := 1; i < n; i++ {
-        xi := x[i*dx+px]
+        xi := x[px+offsets[i]]
         if xi > val {
             val = xi
             idx = i
@@ -22,7 +22,10 @@
 
 package reference
 
-import "fragata/arhat/nnef/dnn/api"
+import (
+    "fmt"
+    "fragata/arhat/nnef/dnn/api"
+)
 
 // interface
 
@@ -32,11 +35,54 @@ func Binary(op api.BinaryOp, x *Tensor, y *Tensor, z *Tensor) error {
     xv.Init(x, rank)
     yv.Init(y, rank)
     zv.Init(z, rank)
-    kernel := getBinaryKernel(op)
+    kernel := getBinaryKernel(op, x.dtype)
+    if kernel == nil {
+        return api.ErrNotSupported
+    }
+    if op == api.OpDiv && y.dtype == api.DtypeInt && hasZeroInt(y.IntData()) {
+        return fmt.Errorf("integer division by zero")
+    }
     binaryLoop(0, &xv, &yv, &zv, kernel)
     return nil
 }
 
+// Singleton operands (such as literals) are broadcast to output volume
+
+func Accumulate(x []*Tensor, y *Tensor) error {
+    n := len(x)
+    assert(n != 0)
+    v := make([][]float32, n)
+    d := make([]int, n)
+    for i := 0; i < n; i++ {
+        switch x[i].volume {
+        case y.volume:
+            d[i] = 1
+        case 1:
+            d[i] = 0
+        default:
+            return fmt.Errorf(
+                "accumulate operand volume %d does not match output volume %d",
+                    x[i].volume, y.volume)
+        }
+        v[i] = x[i].FloatData()
+    }
+    accumulateFloat(y.volume, v, d, y.FloatData())
+    return nil
+}
+
+func Clamp(x *Tensor, a *Tensor, b *Tensor, y *Tensor) error {
+    var xv, av, bv, yv TensorView
+    rank := y.rank
+    xv.Init(x, rank)
+    av.Init(a, rank)
+    bv.Init(b, rank)
+    yv.Init(y, rank)
+    // clamp is ternary like select: x takes place of condition,
+    // bounds a and b of branches, each broadcast independently
+    selectLoop(0, &xv, &av, &bv, &yv, clampFloat)
+    return nil
+}
+
 // binary loop
 
 type binaryKernel func(
@@ -51,26 +97,76 @@ type binaryKernel func(
     pz int,
     dz int)
 
-var binaryKernels = [...]binaryKernel{
-    api.OpAdd: binaryAddFloat,
-    api.OpSub: binarySubFloat,
-    api.OpMul: binaryMulFloat,
-    api.OpDiv: binaryDivFloat,
-    api.OpPow: binaryPowFloat,
-    api.OpMin: binaryMinFloat,
-    api.OpMax: binaryMaxFloat,
-    api.OpAnd: binaryAndBool,
-    api.OpOr: binaryOrBool,
-    api.OpLt: binaryLtFloat,
-    api.OpGt: binaryGtFloat,
-    api.OpLe: binaryLeFloat,
-    api.OpGe: binaryGeFloat,
-    api.OpEq: binaryEqFloat,
-    api.OpNe: binaryNeFloat,
+var binaryKernels = [...][dtypeCount]binaryKernel{
+    api.OpAdd: {
+        api.DtypeInt: binaryAddInt,
+        api.DtypeFloat: binaryAddFloat,
+    },
+    api.OpSub: {
+        api.DtypeInt: binarySubInt,
+        api.DtypeFloat: binarySubFloat,
+    },
+    api.OpMul: {
+        api.DtypeInt: binaryMulInt,
+        api.DtypeFloat: binaryMulFloat,
+    },
+    api.OpDiv: {
+        api.DtypeInt: binaryDivInt,
+        api.DtypeFloat: binaryDivFloat,
+    },
+    api.OpPow: {api.DtypeFloat: binaryPowFloat},
+    api.OpMin: {
+        api.DtypeInt: binaryMinInt,
+        api.DtypeFloat: binaryMinFloat,
+    },
+    api.OpMax: {
+        api.DtypeInt: binaryMaxInt,
+        api.DtypeFloat: binaryMaxFloat,
+    },
+    api.OpAnd: {api.DtypeBool: binaryAndBool},
+    api.OpOr: {api.DtypeBool: binaryOrBool},
+    api.OpLt: {
+        api.DtypeInt: binaryLtInt,
+        api.DtypeFloat: binaryLtFloat,
+    },
+    api.OpGt: {
+        api.DtypeInt: binaryGtInt,
+        api.DtypeFloat: binaryGtFloat,
+    },
+    api.OpLe: {
+        api.DtypeInt: binaryLeInt,
+        api.DtypeFloat: binaryLeFloat,
+    },
+    api.OpGe: {
+        api.DtypeInt: binaryGeInt,
+        api.DtypeFloat: binaryGeFloat,
+    },
+    api.OpEq: {
+        api.DtypeBool: binaryEqBool,
+        api.DtypeInt: binaryEqInt,
+        api.DtypeFloat: binaryEqFloat,
+    },
+    api.OpNe: {
+        api.DtypeBool: binaryNeBool,
+        api.DtypeInt: binaryNeInt,
+        api.DtypeFloat: binaryNeFloat,
+    },
+    api.OpPrelu: {api.DtypeFloat: binaryPreluFloat},
 }
 
-func getBinaryKernel(op api.BinaryOp) binaryKernel {
-    return binaryKernels[op]
+// returns nil if op has no kernel for dtype
+
+func getBinaryKernel(op api.BinaryOp, dtype api.Dtype) binaryKernel {
+    return binaryKernels[op][dtype]
+}
+
+func hasZeroInt(data []int) bool {
+    for _, v := range data {
+        if v == 0 {
+            return true
+        }
+    }
+    return false
 }
 
 func binaryLoop(level int, x *TensorView, y *TensorView, z *TensorView, kernel binaryKernel) {
@@ -127,6 +223,25 @@ func binaryAddFloat(
     }
 }
 
+func binaryAddInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = x[i*dx+px] + y[i*dy+py]
+    }
+}
+
 func binarySubFloat(
         n int, 
         ax interface{}, 
@@ -146,6 +261,25 @@ func binarySubFloat(
     }
 }
 
+func binarySubInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = x[i*dx+px] - y[i*dy+py]
+    }
+}
+
 func binaryMulFloat(
         n int, 
         ax interface{}, 
@@ -165,6 +299,25 @@ func binaryMulFloat(
     }
 }
 
+func binaryMulInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = x[i*dx+px] * y[i*dy+py]
+    }
+}
+
 func binaryDivFloat(
         n int, 
         ax interface{}, 
@@ -184,6 +337,25 @@ func binaryDivFloat(
     }
 }
 
+func binaryDivInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = x[i*dx+px] / y[i*dy+py]
+    }
+}
+
 func binaryPowFloat(
         n int, 
         ax interface{}, 
@@ -222,6 +394,25 @@ func binaryMinFloat(
     }
 }
 
+func binaryMinInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = minInt(x[i*dx+px], y[i*dy+py])
+    }
+}
+
 func binaryMaxFloat(
         n int, 
         ax interface{}, 
@@ -241,6 +432,25 @@ func binaryMaxFloat(
     }
 }
 
+func binaryMaxInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]int)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = maxInt(x[i*dx+px], y[i*dy+py])
+    }
+}
+
 func binaryAndBool(
         n int, 
         ax interface{}, 
@@ -298,6 +508,25 @@ func binaryLtFloat(
     }
 }
 
+func binaryLtInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] < y[i*dy+py])
+    }
+}
+
 func binaryGtFloat(
         n int, 
         ax interface{}, 
@@ -317,6 +546,25 @@ func binaryGtFloat(
     }
 }
 
+func binaryGtInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] > y[i*dy+py])
+    }
+}
+
 func binaryLeFloat(
         n int, 
         ax interface{}, 
@@ -336,6 +584,25 @@ func binaryLeFloat(
     }
 }
 
+func binaryLeInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] <= y[i*dy+py])
+    }
+}
+
 func binaryGeFloat(
         n int, 
         ax interface{}, 
@@ -355,6 +622,25 @@ func binaryGeFloat(
     }
 }
 
+func binaryGeInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] >= y[i*dy+py])
+    }
+}
+
 func binaryEqFloat(
         n int, 
         ax interface{}, 
@@ -374,6 +660,44 @@ func binaryEqFloat(
     }
 }
 
+func binaryEqInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] == y[i*dy+py])
+    }
+}
+
+func binaryEqBool(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]bool)
+    y := ay.([]bool)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] == y[i*dy+py])
+    }
+}
+
 func binaryNeFloat(
         n int, 
         ax interface{}, 
@@ -393,3 +717,99 @@ func binaryNeFloat(
     }
 }
 
+func binaryNeInt(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]int)
+    y := ay.([]int)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] != y[i*dy+py])
+    }
+}
+
+func binaryNeBool(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]bool)
+    y := ay.([]bool)
+    z := az.([]bool)
+    for i := 0; i < n; i++ {
+        z[i*dz+pz] = (x[i*dx+px] != y[i*dy+py])
+    }
+}
+
+func binaryPreluFloat(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        ay interface{}, 
+        py int, 
+        dy int, 
+        az interface{}, 
+        pz int, 
+        dz int) {
+    x := ax.([]float32)
+    y := ay.([]float32)
+    z := az.([]float32)
+    for i := 0; i < n; i++ {
+        t := x[i*dx+px]
+        if t < 0.0 {
+            t *= y[i*dy+py]
+        }
+        z[i*dz+pz] = t
+    }
+}
+
+func accumulateFloat(n int, x [][]float32, dx []int, y []float32) {
+    m := len(x)
+    for i := 0; i < n; i++ {
+        s := x[0][i*dx[0]]
+        for k := 1; k < m; k++ {
+            s += x[k][i*dx[k]]
+        }
+        y[i] = s
+    }
+}
+
+// Follows NNEF definition: y = max(min(x, b), a)
+
+func clampFloat(
+        n int, 
+        ax interface{}, 
+        px int, 
+        dx int, 
+        aa interface{}, 
+        pa int, 
+        da int, 
+        ab interface{}, 
+        pb int, 
+        db int, 
+        ay interface{}, 
+        py int, 
+        dy int) {
+    x := ax.([]float32)
+    a := aa.([]float32)
+    b := ab.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        y[i*dy+py] = max(min(x[i*dx+px], b[i*db+pb]), a[i*da+pa])
+    }
+}
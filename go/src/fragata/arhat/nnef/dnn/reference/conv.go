@@ -22,9 +22,12 @@
 
 package reference
 
+import "fragata/arhat/nnef/dnn/api"
+
 // interface
 
 func Conv(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -32,9 +35,30 @@ func Conv(
         output *Tensor,
         padding []int,
         stride []int,
-        dilation []int) error {
+        dilation []int,
+        border api.Border) error {
+    if useWinograd(transposed, input, filter, output, stride, dilation) {
+        winogradConvLoopFloat(workers, input, filter, bias, output, padding, border)
+        return nil
+    }
+    if useConvGemm(transposed, input, filter, output, 1) {
+        convGemmLoopFloat(
+            workers,
+            transposed,
+            input,
+            filter,
+            bias,
+            output,
+            padding,
+            stride,
+            dilation,
+            1,
+            border)
+        return nil
+    }
     kernel := getConvKernelFloat(transposed, input.rank)
     convLoopFloat(
+        workers,
         transposed,
         input,
         filter,
@@ -43,11 +67,13 @@ func Conv(
         padding,
         stride,
         dilation,
+        border,
         kernel)
     return nil
 }
 
 func DepthwiseConv(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -55,9 +81,11 @@ func DepthwiseConv(
         output *Tensor,
         padding []int,
         stride []int,
-        dilation []int) error {
+        dilation []int,
+        border api.Border) error {
     kernel := getConvKernelFloat(transposed, input.rank)
     depthwiseConvLoopFloat(
+        workers,
         transposed,
         input,
         filter,
@@ -66,11 +94,13 @@ func DepthwiseConv(
         padding,
         stride,
         dilation,
+        border,
         kernel)
     return nil
 }
 
 func GroupedConv(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -79,9 +109,26 @@ func GroupedConv(
         padding []int,
         stride []int,
         dilation []int,
-        groups int) error {
+        groups int,
+        border api.Border) error {
+    if useConvGemm(transposed, input, filter, output, groups) {
+        convGemmLoopFloat(
+            workers,
+            transposed,
+            input,
+            filter,
+            bias,
+            output,
+            padding,
+            stride,
+            dilation,
+            groups,
+            border)
+        return nil
+    }
     kernel := getConvKernelFloat(transposed, input.rank)
     groupedConvLoopFloat(
+        workers,
         transposed,
         input,
         filter,
@@ -91,6 +138,7 @@ func GroupedConv(
         stride,
         dilation,
         groups,
+        border,
         kernel)
     return nil
 }
@@ -106,7 +154,8 @@ type convKernelFloat func(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int)
+        dilation []int,
+        border api.Border)
 
 var convKernelsNFloat = [...]convKernelFloat{
     convCoreN1Float,
@@ -130,6 +179,7 @@ func getConvKernelFloat(transposed bool, rank int) convKernelFloat {
 }
 
 func convLoopFloat(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -138,6 +188,7 @@ func convLoopFloat(
         padding []int,
         stride []int,
         dilation []int,
+        border api.Border,
         kernel convKernelFloat) {
     inputData := input.FloatData()
     filterData := filter.FloatData()
@@ -152,31 +203,52 @@ func convLoopFloat(
     } else {
         convBiasFloat(biasData, outputData, biasShape, outputShape)
     }
-    outputShape0 := outputShape[0]
-    outputShape1 := outputShape[1]
-    inputShape1 := inputShape[1]
-    for b := 0; b < outputShape0; b++ {
-        for z := 0; z < outputShape1; z++ {
-            for c := 0; c < inputShape1; c++ {
-                inputOffset := getConvOffset2(inputShape, b, c)
-                filterOffset := getConvOffset2(filterShape, z, c)
-                outputOffset := getConvOffset2(outputShape, b, z)
-                kernel(
-                    inputData[inputOffset:], 
-                    filterData[filterOffset:],
-                    outputData[outputOffset:],
-                    inputShape[2:],
-                    filterShape[2:],
-                    outputShape[2:],
-                    padding,
-                    stride,
-                    dilation)
+    batch := outputShape[0]
+    outputChannels := outputShape[1]
+    inputChannels := inputShape[1]
+    step := func(b int, z int, c int) {
+        inputOffset := getConvOffset2(inputShape, b, c)
+        filterOffset := getConvOffset2(filterShape, z, c)
+        outputOffset := getConvOffset2(outputShape, b, z)
+        kernel(
+            inputData[inputOffset:], 
+            filterData[filterOffset:],
+            outputData[outputOffset:],
+            inputShape[2:],
+            filterShape[2:],
+            outputShape[2:],
+            padding,
+            stride,
+            dilation,
+            border)
+    }
+    // each task owns one result channel and accumulates into it
+    // in the same order as serial loop would
+    if transposed {
+        parallelFor(workers, batch * inputChannels, func(begin int, end int) {
+            for i := begin; i < end; i++ {
+                b := i / inputChannels
+                c := i % inputChannels
+                for z := 0; z < outputChannels; z++ {
+                    step(b, z, c)
+                }
             }
-        }
+        })
+    } else {
+        parallelFor(workers, batch * outputChannels, func(begin int, end int) {
+            for i := begin; i < end; i++ {
+                b := i / outputChannels
+                z := i % outputChannels
+                for c := 0; c < inputChannels; c++ {
+                    step(b, z, c)
+                }
+            }
+        })
     }
 }
 
 func depthwiseConvLoopFloat(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -185,6 +257,7 @@ func depthwiseConvLoopFloat(
         padding []int,
         stride []int,
         dilation []int,
+        border api.Border,
         kernel convKernelFloat) {
     inputData := input.FloatData()
     filterData := filter.FloatData()
@@ -199,12 +272,15 @@ func depthwiseConvLoopFloat(
     } else {
         convBiasFloat(biasData, outputData, biasShape, outputShape)
     }
-    inputShape0 := inputShape[0]
-    inputShape1 := inputShape[1]
-    multiplier := outputShape[1] / inputShape1
+    batch := inputShape[0]
+    inputChannels := inputShape[1]
+    multiplier := outputShape[1] / inputChannels
     broadcast := (filterShape[0] == 1)
-    for b := 0; b < inputShape0; b++ {
-        for c := 0; c < inputShape1; c++ {
+    // each task owns input channel and output channels derived from it
+    parallelFor(workers, batch * inputChannels, func(begin int, end int) {
+        for i := begin; i < end; i++ {
+            b := i / inputChannels
+            c := i % inputChannels
             for m := 0; m < multiplier; m++ {
                 z := multiplier * c + m
                 inputOffset := getConvOffset2(inputShape, b, c)
@@ -222,13 +298,15 @@ func depthwiseConvLoopFloat(
                     outputShape[2:],
                     padding,
                     stride,
-                    dilation)
+                    dilation,
+                    border)
             }
         }
-    }
+    })
 }
 
 func groupedConvLoopFloat(
+        workers int,
         transposed bool,
         input *Tensor,
         filter *Tensor,
@@ -238,6 +316,7 @@ func groupedConvLoopFloat(
         stride []int,
         dilation []int,
         groups int,
+        border api.Border,
         kernel convKernelFloat) {
     inputData := input.FloatData()
     filterData := filter.FloatData()
@@ -252,29 +331,50 @@ func groupedConvLoopFloat(
     } else {
         convBiasFloat(biasData, outputData, biasShape, outputShape)
     }
-    inputShape0 := input.shape[0]
-    inputBlock := input.shape[1] / groups
-    outputBlock := output.shape[1] / groups
-    for b := 0; b < inputShape0; b++ {
-        for g := 0; g < groups; g++ {
-            for z := 0; z < outputBlock; z++ {
+    batch := input.shape[0]
+    inputChannels := input.shape[1]
+    outputChannels := output.shape[1]
+    inputBlock := inputChannels / groups
+    outputBlock := outputChannels / groups
+    step := func(b int, g int, z int, c int) {
+        inputOffset := getConvOffset2(inputShape, b, g*inputBlock+c)
+        filterOffset := getConvOffset2(filterShape, g*outputBlock+z, c)
+        outputOffset := getConvOffset2(outputShape, b, g*outputBlock+z)
+        kernel(
+            inputData[inputOffset:], 
+            filterData[filterOffset:],
+            outputData[outputOffset:],
+            inputShape[2:],
+            filterShape[2:],
+            outputShape[2:],
+            padding,
+            stride,
+            dilation,
+            border)
+    }
+    // same task partitioning as in convLoopFloat, within groups
+    if transposed {
+        parallelFor(workers, batch * inputChannels, func(begin int, end int) {
+            for i := begin; i < end; i++ {
+                b := i / inputChannels
+                g := (i % inputChannels) / inputBlock
+                c := (i % inputChannels) % inputBlock
+                for z := 0; z < outputBlock; z++ {
+                    step(b, g, z, c)
+                }
+            }
+        })
+    } else {
+        parallelFor(workers, batch * outputChannels, func(begin int, end int) {
+            for i := begin; i < end; i++ {
+                b := i / outputChannels
+                g := (i % outputChannels) / outputBlock
+                z := (i % outputChannels) % outputBlock
                 for c := 0; c < inputBlock; c++ {
-                    inputOffset := getConvOffset2(inputShape, b, g*inputBlock+c)
-                    filterOffset := getConvOffset2(filterShape, g*outputBlock+z, c)
-                    outputOffset := getConvOffset2(outputShape, b, g*outputBlock+z)
-                    kernel(
-                        inputData[inputOffset:], 
-                        filterData[filterOffset:],
-                        outputData[outputOffset:],
-                        inputShape[2:],
-                        filterShape[2:],
-                        outputShape[2:],
-                        padding,
-                        stride,
-                        dilation)
+                    step(b, g, z, c)
                 }
             }
-        }
+        })
     }
 }
 
@@ -312,7 +412,8 @@ func convCoreN1Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     inputShape0 := inputShape[0]
     filterShape0 := filterShape[0]
     outputShape0 := outputShape[0]
@@ -322,8 +423,12 @@ func convCoreN1Float(
     for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
         sum := outputData[outputIndex]
         for filterIndex := 0; filterIndex < filterShape0; filterIndex++ {
-            inputIndex := outputIndex * stride0 + filterIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
+            inputIndex, ok := 
+                borderIndex(
+                    border, 
+                    outputIndex * stride0 + filterIndex * dilation0 - padding0, 
+                    inputShape0)
+            if ok {
                 sum += inputData[inputIndex] * filterData[filterIndex]
             }
         }
@@ -340,7 +445,8 @@ func convCoreN2Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     var inputIndex [2]int
     var outputLoop, filterLoop NdLoop2
     for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
@@ -351,8 +457,7 @@ func convCoreN2Float(
             filterIndex := filterLoop.Index()
             inputIndex[0] = outputIndex[0] * stride[0] + filterIndex[0] * dilation[0] - padding[0]
             inputIndex[1] = outputIndex[1] * stride[1] + filterIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
+            if borderIndexN(border, inputIndex[:], inputShape) {
                 inputOffset := NdOffset2(inputShape, inputIndex[:])
                 filterOffset := NdOffset2(filterShape, filterIndex)
                 sum += inputData[inputOffset] * filterData[filterOffset]
@@ -371,7 +476,8 @@ func convCoreN3Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     var inputIndex [3]int
     var outputLoop, filterLoop NdLoop3
     for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
@@ -383,9 +489,7 @@ func convCoreN3Float(
             inputIndex[0] = outputIndex[0] * stride[0] + filterIndex[0] * dilation[0] - padding[0]
             inputIndex[1] = outputIndex[1] * stride[1] + filterIndex[1] * dilation[1] - padding[1]
             inputIndex[2] = outputIndex[2] * stride[2] + filterIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
+            if borderIndexN(border, inputIndex[:], inputShape) {
                 inputOffset := NdOffset3(inputShape, inputIndex[:])
                 filterOffset := NdOffset3(filterShape, filterIndex)
                 sum += inputData[inputOffset] * filterData[filterOffset]
@@ -406,7 +510,8 @@ func convCoreT1Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     inputShape0 := inputShape[0]
     filterShape0 := filterShape[0]
     outputShape0 := outputShape[0]
@@ -415,8 +520,12 @@ func convCoreT1Float(
     dilation0 := dilation[0]
     for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
         for filterIndex := 0; filterIndex < filterShape0; filterIndex++ {
-            inputIndex := outputIndex * stride0 + filterIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
+            inputIndex, ok := 
+                borderIndex(
+                    border, 
+                    outputIndex * stride0 + filterIndex * dilation0 - padding0, 
+                    inputShape0)
+            if ok {
                 inputData[inputIndex] += outputData[outputIndex] * filterData[filterIndex]
             }
         }
@@ -432,7 +541,8 @@ func convCoreT2Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     var inputIndex [2]int
     var outputLoop, filterLoop NdLoop2
     for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
@@ -442,8 +552,7 @@ func convCoreT2Float(
             filterIndex := filterLoop.Index()
             inputIndex[0] = outputIndex[0] * stride[0] + filterIndex[0] * dilation[0] - padding[0]
             inputIndex[1] = outputIndex[1] * stride[1] + filterIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
+            if borderIndexN(border, inputIndex[:], inputShape) {
                 inputOffset := NdOffset2(inputShape, inputIndex[:])
                 filterOffset := NdOffset2(filterShape, filterIndex)
                 inputData[inputOffset] += outputData[outputOffset] * filterData[filterOffset]
@@ -461,7 +570,8 @@ func convCoreT3Float(
         outputShape []int,
         padding []int,
         stride []int,
-        dilation []int) {
+        dilation []int,
+        border api.Border) {
     var inputIndex [3]int
     var outputLoop, filterLoop NdLoop3
     for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
@@ -472,9 +582,7 @@ func convCoreT3Float(
             inputIndex[0] = outputIndex[0] * stride[0] + filterIndex[0] * dilation[0] - padding[0]
             inputIndex[1] = outputIndex[1] * stride[1] + filterIndex[1] * dilation[1] - padding[1]
             inputIndex[2] = outputIndex[2] * stride[2] + filterIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
+            if borderIndexN(border, inputIndex[:], inputShape) {
                 inputOffset := NdOffset3(inputShape, inputIndex[:])
                 filterOffset := NdOffset3(filterShape, filterIndex)
                 inputData[inputOffset] += outputData[outputOffset] * filterData[filterOffset]
@@ -482,4 +590,3 @@ func convCoreT3Float(
         }
     }
 }
-
@@ -0,0 +1,305 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+import "fragata/arhat/nnef/dnn/api"
+
+//
+//    Convolution via GEMM
+//
+
+// For each batch item and group, convolution multiplies filter block
+// [outputBlock x inputBlock*kernelVolume] by matrix of input patches
+// [inputBlock*kernelVolume x outputVolume] built by im2col. Transposed
+// convolution multiplies transposed filter block by output and scatters
+// resulting patches into input (col2im).
+
+// implementation
+
+// Narrowest column tile used to spread work across workers
+
+const convGemmMinTile = 16
+
+// GEMM path pays off when all dimensions of lowered product are large enough;
+// choice depends on shapes only, never on number of workers
+
+func useConvGemm(transposed bool, input *Tensor, filter *Tensor, output *Tensor, groups int) bool {
+    inputBlock := input.shape[1] / groups
+    outputBlock := output.shape[1] / groups
+    patch := inputBlock * volumeOf(filter.shape[2:])
+    outputVolume := volumeOf(output.shape[2:])
+    if transposed {
+        return useGemm(patch, outputVolume, outputBlock)
+    }
+    return useGemm(outputBlock, outputVolume, patch)
+}
+
+func convGemmLoopFloat(
+        workers int,
+        transposed bool,
+        input *Tensor,
+        filter *Tensor,
+        bias *Tensor,
+        output *Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        groups int,
+        border api.Border) {
+    if transposed {
+        convBiasFloat(bias.FloatData(), input.FloatData(), bias.shape, input.shape)
+        convGemmTFloat(workers, input, filter, output, padding, stride, dilation, groups, border)
+    } else {
+        convBiasFloat(bias.FloatData(), output.FloatData(), bias.shape, output.shape)
+        convGemmNFloat(workers, input, filter, output, padding, stride, dilation, groups, border)
+    }
+}
+
+// Returns width of tiles splitting count columns of each of items. Tiles
+// are narrowed when there are too few of them to occupy all workers;
+// callers ensure that tile width does not change order of accumulation,
+// so results do not depend on workers.
+
+func convTileWidth(workers int, items int, count int, maxWidth int, minWidth int) int {
+    width := minInt(count, maxWidth)
+    if items * ((count + width - 1) / width) < workers {
+        perItem := (workers + items - 1) / items
+        width = (count + perItem - 1) / perItem
+        width = minInt(count, maxInt(width, minWidth))
+    }
+    return width
+}
+
+// Tasks are column tiles of all batch items and groups
+
+func convGemmNFloat(
+        workers int,
+        input *Tensor,
+        filter *Tensor,
+        output *Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        groups int,
+        border api.Border) {
+    inputData := input.FloatData()
+    filterData := filter.FloatData()
+    outputData := output.FloatData()
+    inputBlock := input.shape[1] / groups
+    outputBlock := output.shape[1] / groups
+    patch := inputBlock * volumeOf(filter.shape[2:])
+    outputVolume := volumeOf(output.shape[2:])
+    items := output.shape[0] * groups
+    width := convTileWidth(workers, items, outputVolume, gemmBlockN, convGemmMinTile)
+    tiles := (outputVolume + width - 1) / width
+    parallelFor(workers, items * tiles, func(begin int, end int) {
+        cols := make([]float32, patch*width)
+        for t := begin; t < end; t++ {
+            b := (t / tiles) / groups
+            g := (t / tiles) % groups
+            j0 := (t % tiles) * width
+            nc := minInt(width, outputVolume-j0)
+            inputOffset := getConvOffset2(input.shape, b, g*inputBlock)
+            filterOffset := getConvOffset2(filter.shape, g*outputBlock, 0)
+            outputOffset := getConvOffset2(output.shape, b, g*outputBlock)
+            im2colFloat(
+                inputData[inputOffset:],
+                cols,
+                input.shape[2:],
+                filter.shape[2:],
+                output.shape[2:],
+                padding,
+                stride,
+                dilation,
+                border,
+                inputBlock,
+                j0,
+                nc)
+            gemmPackedFloat(
+                false,
+                patch,
+                0,
+                outputBlock,
+                filterData[filterOffset:],
+                patch,
+                cols[:patch*nc],
+                nc,
+                outputData[outputOffset+j0:],
+                outputVolume)
+        }
+    })
+}
+
+// Patches of distinct columns overlap in input, therefore column tiles
+// are processed serially; tasks are input channels within each tile.
+
+func convGemmTFloat(
+        workers int,
+        input *Tensor,
+        filter *Tensor,
+        output *Tensor,
+        padding []int,
+        stride []int,
+        dilation []int,
+        groups int,
+        border api.Border) {
+    inputData := input.FloatData()
+    filterData := filter.FloatData()
+    outputData := output.FloatData()
+    inputBlock := input.shape[1] / groups
+    outputBlock := output.shape[1] / groups
+    kernelVolume := volumeOf(filter.shape[2:])
+    patch := inputBlock * kernelVolume
+    outputVolume := volumeOf(output.shape[2:])
+    width := minInt(outputVolume, gemmBlockN)
+    cols := make([]float32, patch*width)
+    for b := 0; b < output.shape[0]; b++ {
+        for g := 0; g < groups; g++ {
+            inputOffset := getConvOffset2(input.shape, b, g*inputBlock)
+            filterOffset := getConvOffset2(filter.shape, g*outputBlock, 0)
+            outputOffset := getConvOffset2(output.shape, b, g*outputBlock)
+            for j0 := 0; j0 < outputVolume; j0 += width {
+                nc := minInt(width, outputVolume-j0)
+                parallelFor(workers, inputBlock, func(begin int, end int) {
+                    r0 := begin * kernelVolume
+                    r1 := end * kernelVolume
+                    fillFloat(cols[r0*nc:r1*nc], float32(0.0))
+                    gemmFloat(
+                        true,
+                        false,
+                        patch,
+                        nc,
+                        outputBlock,
+                        r0,
+                        r1,
+                        filterData[filterOffset:],
+                        patch,
+                        outputData[outputOffset+j0:],
+                        outputVolume,
+                        cols,
+                        nc)
+                    col2imFloat(
+                        inputData[inputOffset:],
+                        cols,
+                        input.shape[2:],
+                        filter.shape[2:],
+                        output.shape[2:],
+                        padding,
+                        stride,
+                        dilation,
+                        border,
+                        begin,
+                        end,
+                        j0,
+                        nc)
+                })
+            }
+        }
+    }
+}
+
+// kernels
+
+// Fills nc columns of patch matrix for outputs starting at j0; row
+// c * kernelVolume + kk holds input of channel c at kernel position kk.
+
+func im2colFloat(
+        inputData []float32,
+        cols []float32,
+        inputShape []int,
+        filterShape []int,
+        outputShape []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border,
+        channels int,
+        j0 int,
+        nc int) {
+    rank := len(inputShape)
+    inputVolume := volumeOf(inputShape)
+    rowStep := volumeOf(filterShape) * nc
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, j0)
+    for j := 0; j < nc; j++ {
+        outputIndex := outputLoop.Index()
+        kk := 0
+        for kernelLoop.Start(filterShape); kernelLoop.Test(); kernelLoop.Next() {
+            kernelIndex := kernelLoop.Index()
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            p := kk * nc + j
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
+                for c := 0; c < channels; c++ {
+                    cols[p] = inputData[c*inputVolume+inputOffset]
+                    p += rowStep
+                }
+            } else {
+                for c := 0; c < channels; c++ {
+                    cols[p] = float32(0.0)
+                    p += rowStep
+                }
+            }
+            kk++
+        }
+        outputLoop.Next()
+    }
+}
+
+// Accumulates rows of channels [c0, c1) of patch matrix into input
+
+func col2imFloat(
+        inputData []float32,
+        cols []float32,
+        inputShape []int,
+        filterShape []int,
+        outputShape []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border,
+        c0 int,
+        c1 int,
+        j0 int,
+        nc int) {
+    rank := len(inputShape)
+    inputVolume := volumeOf(inputShape)
+    rowStep := volumeOf(filterShape) * nc
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, j0)
+    for j := 0; j < nc; j++ {
+        outputIndex := outputLoop.Index()
+        kk := 0
+        for kernelLoop.Start(filterShape); kernelLoop.Test(); kernelLoop.Next() {
+            kernelIndex := kernelLoop.Index()
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
+                p := c0 * rowStep + kk * nc + j
+                for c := c0; c < c1; c++ {
+                    inputData[c*inputVolume+inputOffset] += cols[p]
+                    p += rowStep
+                }
+            }
+            kk++
+        }
+        outputLoop.Next()
+    }
+}
+
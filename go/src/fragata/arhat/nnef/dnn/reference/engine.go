@@ -28,14 +28,39 @@ import "fragata/arhat/nnef/dnn/api"
 //    Engine
 //
 
-type Engine struct {}
+type Engine struct {
+    workers int
+}
+
+// workers: number of goroutines used by conv, matmul and pooling
+//     kernels; if not positive, number of CPUs is used
 
-func NewEngine() *Engine {
-    return new(Engine)
+func NewEngine(workers int) *Engine {
+    e := new(Engine)
+    if workers <= 0 {
+        workers = defaultWorkers()
+    }
+    e.workers = workers
+    return e
+}
+
+func(e *Engine) Workers() int {
+    return e.workers
 }
 
 // interface
 
+func(e *Engine) Supports(op string, attribs map[string]string) bool {
+    if !supportedOps[op] {
+        return false
+    }
+    if op == "multilinear_upsample" {
+        border, ok := attribs["border"]
+        return (!ok || border == "constant" || border == "replicate")
+    }
+    return true
+}
+
 func(e *Engine) NewTensor(dtype api.Dtype, shape []int) (api.Tensor, error) {
     tensor, err := NewTensor(dtype, shape)
     if err != nil {
@@ -44,6 +69,26 @@ func(e *Engine) NewTensor(dtype api.Dtype, shape []int) (api.Tensor, error) {
     return tensor, nil
 }
 
+func(e *Engine) NewBuffer(dtype api.Dtype, volume int) (api.Buffer, error) {
+    buffer, err := NewBuffer(dtype, volume)
+    if err != nil {
+        return nil, err
+    }
+    return buffer, nil
+}
+
+func(e *Engine) ItemSize(dtype api.Dtype) int {
+    return itemSize(dtype)
+}
+
+func(e *Engine) NewTensorIn(buffer api.Buffer, shape []int) (api.Tensor, error) {
+    tensor, err := NewTensorIn(buffer.(*Buffer), shape)
+    if err != nil {
+        return nil, err
+    }
+    return tensor, nil
+}
+
 func(e *Engine) Fill(tensor api.Tensor, data interface{}) error {
     return Fill(tensor.(*Tensor), data)
 }
@@ -60,14 +105,39 @@ func(e *Engine) Unary(op api.UnaryOp, x api.Tensor, y api.Tensor) error {
     return Unary(op, x.(*Tensor), y.(*Tensor))
 }
 
+func(e *Engine) UnaryParam(
+        op api.UnaryParamOp, 
+        x api.Tensor, 
+        y api.Tensor, 
+        alpha float32) error {
+    return UnaryParam(op, x.(*Tensor), y.(*Tensor), alpha)
+}
+
 func(e *Engine) Binary(op api.BinaryOp, x api.Tensor, y api.Tensor, z api.Tensor) error {
     return Binary(op, x.(*Tensor), y.(*Tensor), z.(*Tensor))
 }
 
+func(e *Engine) Accumulate(x []api.Tensor, y api.Tensor) error {
+    n := len(x)
+    v := make([]*Tensor, n)
+    for i := 0; i < n; i++ {
+        v[i] = x[i].(*Tensor)
+    }
+    return Accumulate(v, y.(*Tensor))
+}
+
+func(e *Engine) Clamp(x api.Tensor, a api.Tensor, b api.Tensor, y api.Tensor) error {
+    return Clamp(x.(*Tensor), a.(*Tensor), b.(*Tensor), y.(*Tensor))
+}
+
 func(e *Engine) Reduce(op api.ReduceOp, input api.Tensor, output api.Tensor) error {
     return Reduce(op, input.(*Tensor), output.(*Tensor))
 }
 
+func(e *Engine) Moments(input api.Tensor, mean api.Tensor, variance api.Tensor) error {
+    return Moments(input.(*Tensor), mean.(*Tensor), variance.(*Tensor))
+}
+
 func(e *Engine) Select(c api.Tensor, x api.Tensor, y api.Tensor, z api.Tensor) error {
     return Select(c.(*Tensor), x.(*Tensor), y.(*Tensor), z.(*Tensor))
 }
@@ -80,8 +150,10 @@ func(e *Engine) Conv(
         output api.Tensor,
         padding []int,
         stride []int,
-        dilation []int) error {
+        dilation []int,
+        border api.Border) error {
     return Conv(
+        e.workers,
         transposed,
         input.(*Tensor),
         filter.(*Tensor),
@@ -89,7 +161,8 @@ func(e *Engine) Conv(
         output.(*Tensor),
         padding,
         stride,
-        dilation)
+        dilation,
+        border)
 }
 
 func(e *Engine) DepthwiseConv(
@@ -100,8 +173,10 @@ func(e *Engine) DepthwiseConv(
         output api.Tensor,
         padding []int,
         stride []int,
-        dilation []int) error {
+        dilation []int,
+        border api.Border) error {
     return DepthwiseConv(
+        e.workers,
         transposed,
         input.(*Tensor),
         filter.(*Tensor),
@@ -109,7 +184,8 @@ func(e *Engine) DepthwiseConv(
         output.(*Tensor),
         padding,
         stride,
-        dilation)
+        dilation,
+        border)
 }
 
 func(e *Engine) GroupedConv(
@@ -121,8 +197,10 @@ func(e *Engine) GroupedConv(
         padding []int,
         stride []int,
         dilation []int,
-        groups int) error {
+        groups int,
+        border api.Border) error {
     return GroupedConv(
+        e.workers,
         transposed,
         input.(*Tensor),
         filter.(*Tensor),
@@ -131,7 +209,8 @@ func(e *Engine) GroupedConv(
         padding,
         stride,
         dilation,
-        groups)
+        groups,
+        border)
 }
 
 func(e *Engine) Pool(
@@ -143,8 +222,9 @@ func(e *Engine) Pool(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) error {
+        border api.Border) error {
     return Pool(
+        e.workers,
         op,
         transposed,
         input.(*Tensor),
@@ -153,11 +233,118 @@ func(e *Engine) Pool(
         padding,
         stride,
         dilation,
-        includeBorder)
+        border)
+}
+
+func(e *Engine) MaxPoolWithIndex(
+        input api.Tensor,
+        output api.Tensor,
+        index api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    var outputTensor *Tensor
+    if output != nil {
+        outputTensor = output.(*Tensor)
+    }
+    return MaxPoolWithIndex(
+        input.(*Tensor),
+        outputTensor,
+        index.(*Tensor),
+        size,
+        padding,
+        stride,
+        dilation,
+        border)
+}
+
+func(e *Engine) Sample(
+        transposed bool,
+        input api.Tensor,
+        index api.Tensor,
+        output api.Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    return Sample(
+        transposed,
+        input.(*Tensor),
+        index.(*Tensor),
+        output.(*Tensor),
+        size,
+        padding,
+        stride,
+        dilation,
+        border)
+}
+
+func(e *Engine) BatchNorm(
+        input api.Tensor,
+        mean api.Tensor,
+        variance api.Tensor,
+        offset api.Tensor,
+        scale api.Tensor,
+        output api.Tensor,
+        epsilon float32) error {
+    return BatchNorm(
+        input.(*Tensor),
+        mean.(*Tensor),
+        variance.(*Tensor),
+        offset.(*Tensor),
+        scale.(*Tensor),
+        output.(*Tensor),
+        epsilon)
+}
+
+func(e *Engine) LocalNorm(
+        op api.LocalNormOp,
+        input api.Tensor,
+        output api.Tensor,
+        size []int,
+        alpha float32,
+        beta float32,
+        bias float32,
+        epsilon float32) error {
+    return LocalNorm(
+        op, 
+        input.(*Tensor), 
+        output.(*Tensor), 
+        size, 
+        alpha, 
+        beta, 
+        bias, 
+        epsilon)
+}
+
+func(e *Engine) Norm(
+        op api.NormOp,
+        input api.Tensor,
+        output api.Tensor,
+        axes []int,
+        bias float32,
+        epsilon float32) error {
+    return Norm(op, input.(*Tensor), output.(*Tensor), axes, bias, epsilon)
+}
+
+func(e *Engine) LinearQuantize(
+        x api.Tensor, 
+        min api.Tensor, 
+        max api.Tensor, 
+        y api.Tensor, 
+        bits int) error {
+    return LinearQuantize(x.(*Tensor), min.(*Tensor), max.(*Tensor), y.(*Tensor), bits)
+}
+
+func(e *Engine) LogarithmicQuantize(x api.Tensor, max api.Tensor, y api.Tensor, bits int) error {
+    return LogarithmicQuantize(x.(*Tensor), max.(*Tensor), y.(*Tensor), bits)
 }
 
 func(e *Engine) Matmul(trA bool, trB bool, a api.Tensor, b api.Tensor, c api.Tensor) error {
-    return Matmul(trA, trB, a.(*Tensor), b.(*Tensor), c.(*Tensor))
+    return Matmul(e.workers, trA, trB, a.(*Tensor), b.(*Tensor), c.(*Tensor))
 }
 
 func(e *Engine) Linear(
@@ -165,15 +352,15 @@ func(e *Engine) Linear(
         filter api.Tensor, 
         bias api.Tensor, 
         output api.Tensor) error {
-    return Linear(input.(*Tensor), filter.(*Tensor), bias.(*Tensor), output.(*Tensor))
+    return Linear(e.workers, input.(*Tensor), filter.(*Tensor), bias.(*Tensor), output.(*Tensor))
 }
 
-func(e *Engine) Softmax(input api.Tensor, output api.Tensor, axis int) error {
-    return Softmax(input.(*Tensor), output.(*Tensor), axis)
+func(e *Engine) Softmax(input api.Tensor, output api.Tensor, axes []int) error {
+    return Softmax(input.(*Tensor), output.(*Tensor), axes)
 }
 
-func(e *Engine) ArgReduce(op api.ArgReduceOp, input api.Tensor, output api.Tensor, axis int) error {
-    return ArgReduce(op, input.(*Tensor), output.(*Tensor), axis)
+func(e *Engine) ArgReduce(op api.ArgReduceOp, input api.Tensor, output api.Tensor, axes []int) error {
+    return ArgReduce(op, input.(*Tensor), output.(*Tensor), axes)
 }
 
 func(e *Engine) Transpose(input api.Tensor, output api.Tensor, perm []int) error {
@@ -200,6 +387,10 @@ func(e *Engine) PadReplicate(input api.Tensor, output api.Tensor, padding []int)
     return PadReplicate(input.(*Tensor), output.(*Tensor), padding)
 }
 
+func(e *Engine) PadReflect(input api.Tensor, output api.Tensor, padding []int, even bool) error {
+    return PadReflect(input.(*Tensor), output.(*Tensor), padding, even)
+}
+
 func(e *Engine) Tile(input api.Tensor, output api.Tensor) error {
     return Tile(input.(*Tensor), output.(*Tensor))
 }
@@ -208,8 +399,160 @@ func(e *Engine) Slice(input api.Tensor, output api.Tensor, offset []int) error {
     return Slice(input.(*Tensor), output.(*Tensor), offset)
 }
 
+func(e *Engine) MultilinearUpsample(
+        method api.UpsampleMethod,
+        border api.Border,
+        input api.Tensor,
+        output api.Tensor,
+        factor []int) error {
+    return MultilinearUpsample(method, border, input.(*Tensor), output.(*Tensor), factor)
+}
+
+func(e *Engine) RoiPool(
+        op api.PoolOp,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor) error {
+    return RoiPool(op, input.(*Tensor), rois.(*Tensor), batchIndex.(*Tensor), output.(*Tensor))
+}
+
+func(e *Engine) RoiResample(
+        method api.UpsampleMethod,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor) error {
+    return RoiResample(
+        method, 
+        input.(*Tensor), 
+        rois.(*Tensor), 
+        batchIndex.(*Tensor), 
+        output.(*Tensor))
+}
+
+func(e *Engine) RoiAlign(
+        op api.PoolOp,
+        method api.UpsampleMethod,
+        input api.Tensor,
+        rois api.Tensor,
+        batchIndex api.Tensor,
+        output api.Tensor,
+        samplingRate []int) error {
+    return RoiAlign(
+        op,
+        method, 
+        input.(*Tensor), 
+        rois.(*Tensor), 
+        batchIndex.(*Tensor), 
+        output.(*Tensor),
+        samplingRate)
+}
+
 // implementation
 
+// operations of NNEF stdlib implemented natively
+
+var supportedOps = map[string]bool{
+    "external": true,
+    "constant": true,
+    "variable": true,
+    "copy": true,
+    "neg": true,
+    "not": true,
+    "rcp": true,
+    "exp": true,
+    "log": true,
+    "sin": true,
+    "cos": true,
+    "abs": true,
+    "sign": true,
+    "floor": true,
+    "ceil": true,
+    "round": true,
+    "sqr": true,
+    "sqrt": true,
+    "rsqr": true,
+    "rsqrt": true,
+    "log2": true,
+    "relu": true,
+    "sigmoid": true,
+    "tanh": true,
+    "elu": true,
+    "softabs": true,
+    "softplus": true,
+    "leaky_relu": true,
+    "prelu": true,
+    "linear_quantize": true,
+    "logarithmic_quantize": true,
+    "add": true,
+    "sub": true,
+    "mul": true,
+    "div": true,
+    "min": true,
+    "max": true,
+    "pow": true,
+    "lt": true,
+    "le": true,
+    "gt": true,
+    "ge": true,
+    "eq": true,
+    "ne": true,
+    "and": true,
+    "or": true,
+    "select": true,
+    "clamp": true,
+    "conv": true,
+    "deconv": true,
+    "box": true,
+    "debox": true,
+    "max_pool": true,
+    "avg_pool": true,
+    "argmax_pool": true,
+    "max_pool_with_index": true,
+    "sample": true,
+    "desample": true,
+    "sum_reduce": true,
+    "min_reduce": true,
+    "max_reduce": true,
+    "mean_reduce": true,
+    "argmax_reduce": true,
+    "argmin_reduce": true,
+    "any_reduce": true,
+    "all_reduce": true,
+    "moments": true,
+    "multilinear_upsample": true,
+    "local_response_normalization": true,
+    "local_mean_normalization": true,
+    "local_variance_normalization": true,
+    "local_contrast_normalization": true,
+    "l1_normalization": true,
+    "l2_normalization": true,
+    "batch_normalization": true,
+    "avg_roi_pool": true,
+    "max_roi_pool": true,
+    "avg_roi_align": true,
+    "max_roi_align": true,
+    "roi_resample": true,
+    "reshape": true,
+    "squeeze": true,
+    "unsqueeze": true,
+    "transpose": true,
+    "split": true,
+    "concat": true,
+    "stack": true,
+    "unstack": true,
+    "slice": true,
+    "tile": true,
+    "pad": true,
+    "copy_n": true,
+    "add_n": true,
+    "matmul": true,
+    "linear": true,
+    "softmax": true,
+    "update": true,
+}
+
 func castTensors(x []api.Tensor) []*Tensor {
     n := len(x)
     y := make([]*Tensor, n)
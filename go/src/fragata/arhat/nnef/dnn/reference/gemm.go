@@ -0,0 +1,192 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+//
+//    Blocked matrix multiplication
+//
+
+// Panels of B (gemmBlockK x gemmBlockN) and strips of A (gemmBlockM x gemmBlockK)
+// are packed into contiguous buffers sized to stay in cache.
+
+const (
+    gemmBlockM = 64
+    gemmBlockN = 256
+    gemmBlockK = 256
+)
+
+// Smallest dimension for which packing pays off
+
+const gemmMinSize = 16
+
+func useGemm(m int, n int, k int) bool {
+    return (m >= gemmMinSize && n >= gemmMinSize && k >= gemmMinSize)
+}
+
+// Computes rows [i0, i1) of C += op(A) * op(B) where op(A) is m x k,
+// op(B) is k x n and all matrices are row-major with given leading dimensions.
+// Each item of C accumulates products in ascending order of k, therefore
+// results match those of the plain loop kernels.
+
+func gemmFloat(
+        trA bool,
+        trB bool,
+        m int,
+        n int,
+        k int,
+        i0 int,
+        i1 int,
+        a []float32,
+        lda int,
+        b []float32,
+        ldb int,
+        c []float32,
+        ldc int) {
+    if i0 >= i1 || n == 0 || k == 0 {
+        return
+    }
+    ap := make([]float32, gemmBlockM*minInt(k, gemmBlockK))
+    bp := make([]float32, minInt(k, gemmBlockK)*minInt(n, gemmBlockN))
+    for j0 := 0; j0 < n; j0 += gemmBlockN {
+        nc := minInt(gemmBlockN, n-j0)
+        for l0 := 0; l0 < k; l0 += gemmBlockK {
+            kc := minInt(gemmBlockK, k-l0)
+            gemmPackB(trB, b, ldb, l0, kc, j0, nc, bp)
+            gemmPanelFloat(trA, a, lda, i0, i1, l0, kc, bp[:kc*nc], nc, c[j0:], ldc, ap)
+        }
+    }
+}
+
+// Same as gemmFloat with op(B) supplied as contiguous k x n matrix,
+// which already has layout of packed panels.
+
+func gemmPackedFloat(
+        trA bool,
+        k int,
+        i0 int,
+        i1 int,
+        a []float32,
+        lda int,
+        bp []float32,
+        n int,
+        c []float32,
+        ldc int) {
+    if i0 >= i1 || n == 0 || k == 0 {
+        return
+    }
+    ap := make([]float32, gemmBlockM*minInt(k, gemmBlockK))
+    for l0 := 0; l0 < k; l0 += gemmBlockK {
+        kc := minInt(gemmBlockK, k-l0)
+        gemmPanelFloat(trA, a, lda, i0, i1, l0, kc, bp[l0*n:(l0+kc)*n], n, c, ldc, ap)
+    }
+}
+
+// Multiplies rows [i0, i1) and columns [l0, l0+kc) of op(A) by packed kc x nc panel
+
+func gemmPanelFloat(
+        trA bool,
+        a []float32,
+        lda int,
+        i0 int,
+        i1 int,
+        l0 int,
+        kc int,
+        bp []float32,
+        nc int,
+        c []float32,
+        ldc int,
+        ap []float32) {
+    for r0 := i0; r0 < i1; r0 += gemmBlockM {
+        mc := minInt(gemmBlockM, i1-r0)
+        gemmPackA(trA, a, lda, r0, mc, l0, kc, ap)
+        gemmKernelFloat(mc, nc, kc, ap, bp, c[r0*ldc:], ldc)
+    }
+}
+
+func gemmPackA(trA bool, a []float32, lda int, i0 int, mc int, l0 int, kc int, ap []float32) {
+    p := 0
+    for i := i0; i < i0 + mc; i++ {
+        if trA {
+            for l := l0; l < l0 + kc; l++ {
+                ap[p] = a[l*lda+i]
+                p++
+            }
+        } else {
+            copy(ap[p:p+kc], a[i*lda+l0:i*lda+l0+kc])
+            p += kc
+        }
+    }
+}
+
+func gemmPackB(trB bool, b []float32, ldb int, l0 int, kc int, j0 int, nc int, bp []float32) {
+    p := 0
+    for l := l0; l < l0 + kc; l++ {
+        if trB {
+            for j := j0; j < j0 + nc; j++ {
+                bp[p] = b[j*ldb+l]
+                p++
+            }
+        } else {
+            copy(bp[p:p+nc], b[l*ldb+j0:l*ldb+j0+nc])
+            p += nc
+        }
+    }
+}
+
+// kernels
+
+// Updates mc x nc block of C with product of packed mc x kc strip
+// and packed kc x nc panel, four rows at a time.
+
+func gemmKernelFloat(mc int, nc int, kc int, ap []float32, bp []float32, c []float32, ldc int) {
+    i := 0
+    for ; i + 4 <= mc; i += 4 {
+        c0 := c[i*ldc:i*ldc+nc]
+        c1 := c[(i+1)*ldc:(i+1)*ldc+nc]
+        c2 := c[(i+2)*ldc:(i+2)*ldc+nc]
+        c3 := c[(i+3)*ldc:(i+3)*ldc+nc]
+        a0 := ap[i*kc:(i+1)*kc]
+        a1 := ap[(i+1)*kc:(i+2)*kc]
+        a2 := ap[(i+2)*kc:(i+3)*kc]
+        a3 := ap[(i+3)*kc:(i+4)*kc]
+        for l := 0; l < kc; l++ {
+            x0 := a0[l]
+            x1 := a1[l]
+            x2 := a2[l]
+            x3 := a3[l]
+            bl := bp[l*nc:(l+1)*nc]
+            for j, y := range bl {
+                c0[j] += x0 * y
+                c1[j] += x1 * y
+                c2[j] += x2 * y
+                c3[j] += x3 * y
+            }
+        }
+    }
+    for ; i < mc; i++ {
+        ci := c[i*ldc:i*ldc+nc]
+        ai := ap[i*kc:(i+1)*kc]
+        for l := 0; l < kc; l++ {
+            x := ai[l]
+            bl := bp[l*nc:(l+1)*nc]
+            for j, y := range bl {
+                ci[j] += x * y
+            }
+        }
+    }
+}
+
@@ -0,0 +1,214 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+import (
+    "fmt"
+    "math"
+    "testing"
+    "fragata/arhat/nnef/dnn/api"
+)
+
+var allBorders = []api.Border{
+    api.BorderConstant,
+    api.BorderIgnore,
+    api.BorderReplicate,
+    api.BorderReflect,
+    api.BorderReflectEven,
+}
+
+// GEMM paths accumulate in the same order as plain kernels,
+// so results must be identical
+
+func TestGemmMatmul(t *testing.T) {
+    // sizes span several cache blocks with partial last ones
+    sizes := [][3]int{{16, 16, 16}, {70, 260, 300}, {33, 17, 65}}
+    for _, size := range sizes {
+        m, n, k := size[0], size[1], size[2]
+        if !useGemm(m, n, k) {
+            t.Fatalf("GEMM not used for %d x %d x %d", m, n, k)
+        }
+        for _, trA := range []bool{false, true} {
+            for _, trB := range []bool{false, true} {
+                aShape := []int{m, k}
+                if trA {
+                    aShape = []int{k, m}
+                }
+                bShape := []int{k, n}
+                if trB {
+                    bShape = []int{n, k}
+                }
+                a := randomTensor(aShape, 1)
+                b := randomTensor(bShape, 2)
+                c := zeroTensor([]int{m, n})
+                Matmul(4, trA, trB, a, b, c)
+                expect := zeroTensor([]int{m, n})
+                kernel := getPlainMatmulKernelFloat(trA, trB)
+                kernel(m, n, k, 0, m, a.FloatData(), b.FloatData(), expect.FloatData())
+                name := fmt.Sprintf("matmul %v %v %d x %d x %d", trA, trB, m, n, k)
+                checkIdentical(t, name, c, expect)
+            }
+        }
+    }
+}
+
+func TestGemmLinear(t *testing.T) {
+    m, n, k := 40, 24, 70
+    input := randomTensor([]int{m, k}, 1)
+    filter := randomTensor([]int{n, k}, 2)
+    bias := randomTensor([]int{1, n}, 3)
+    output := zeroTensor([]int{m, n})
+    Linear(4, input, filter, bias, output)
+    expect := zeroTensor([]int{m, n})
+    expectData := expect.FloatData()
+    for i := 0; i < m; i++ {
+        copy(expectData[i*n:(i+1)*n], bias.FloatData())
+    }
+    matmulNTFloat(m, n, k, 0, m, input.FloatData(), filter.FloatData(), expectData)
+    checkIdentical(t, "linear", output, expect)
+}
+
+// Transposed GEMM path sums over output channels before scattering
+// patches into input, so it matches plain kernel only up to rounding
+
+func TestGemmConv(t *testing.T) {
+    tests := []struct {
+        name string
+        transposed bool
+        groups int
+        input []int
+        filter []int
+        output []int
+        padding []int
+        stride []int
+        dilation []int
+    }{
+        {"conv", false, 1, []int{2, 16, 12, 11}, []int{16, 16, 3, 3}, []int{2, 16, 6, 6}, []int{1, 1}, []int{2, 2}, []int{1, 1}},
+        {"dilated conv", false, 1, []int{1, 8, 12, 12}, []int{20, 8, 3, 3}, []int{1, 20, 12, 12}, []int{2, 2}, []int{1, 1}, []int{2, 2}},
+        {"conv 1d", false, 1, []int{2, 16, 40}, []int{16, 16, 5}, []int{2, 16, 40}, []int{2}, []int{1}, []int{1}},
+        {"grouped conv", false, 2, []int{1, 32, 8, 8}, []int{32, 16, 3, 3}, []int{1, 32, 8, 8}, []int{1, 1}, []int{1, 1}, []int{1, 1}},
+        {"deconv", true, 1, []int{2, 16, 6, 6}, []int{16, 16, 3, 3}, []int{2, 16, 12, 12}, []int{1, 1}, []int{2, 2}, []int{1, 1}},
+        {"grouped deconv", true, 2, []int{1, 32, 6, 6}, []int{32, 16, 3, 3}, []int{1, 32, 12, 12}, []int{1, 1}, []int{2, 2}, []int{1, 1}},
+    }
+    for _, test := range tests {
+        for _, border := range allBorders {
+            x := randomTensor(test.input, 1)
+            filter := randomTensor(test.filter, 2)
+            biasChannels := test.filter[0]
+            if test.transposed {
+                biasChannels = test.filter[1] * test.groups
+            }
+            bias := randomTensor([]int{1, biasChannels}, 3)
+            result := zeroTensor(test.output)
+            expect := zeroTensor(test.output)
+            input, output := x, result
+            expectInput, expectOutput := x, expect
+            if test.transposed {
+                input, output = result, x
+                expectInput, expectOutput = expect, x
+            }
+            if !useConvGemm(test.transposed, input, filter, output, test.groups) {
+                t.Fatalf("%s: GEMM not used", test.name)
+            }
+            kernel := getConvKernelFloat(test.transposed, input.rank)
+            if test.groups == 1 {
+                Conv(
+                    4,
+                    test.transposed,
+                    input,
+                    filter,
+                    bias,
+                    output,
+                    test.padding,
+                    test.stride,
+                    test.dilation,
+                    border)
+                convLoopFloat(
+                    1,
+                    test.transposed,
+                    expectInput,
+                    filter,
+                    bias,
+                    expectOutput,
+                    test.padding,
+                    test.stride,
+                    test.dilation,
+                    border,
+                    kernel)
+            } else {
+                GroupedConv(
+                    4,
+                    test.transposed,
+                    input,
+                    filter,
+                    bias,
+                    output,
+                    test.padding,
+                    test.stride,
+                    test.dilation,
+                    test.groups,
+                    border)
+                groupedConvLoopFloat(
+                    1,
+                    test.transposed,
+                    expectInput,
+                    filter,
+                    bias,
+                    expectOutput,
+                    test.padding,
+                    test.stride,
+                    test.dilation,
+                    test.groups,
+                    border,
+                    kernel)
+            }
+            name := fmt.Sprintf("%s, border %d", test.name, border)
+            if test.transposed {
+                checkClose(t, name, result, expect, 1.0e-5)
+            } else {
+                checkIdentical(t, name, result, expect)
+            }
+        }
+    }
+}
+
+// Checks that |x - y| <= tol * (1 + |y|) for all items
+
+func checkClose(t *testing.T, name string, x *Tensor, y *Tensor, tol float64) {
+    t.Helper()
+    xData := x.FloatData()
+    yData := y.FloatData()
+    for i := range xData {
+        d := math.Abs(float64(xData[i]) - float64(yData[i]))
+        if d > tol * (1.0 + math.Abs(float64(yData[i]))) {
+            t.Fatalf("%s: item %d differs: %g != %g", name, i, xData[i], yData[i])
+        }
+    }
+}
+
+func getPlainMatmulKernelFloat(trA bool, trB bool) matmulKernelFloat {
+    if trA {
+        if trB {
+            return matmulTTFloat
+        }
+        return matmulTNFloat
+    }
+    if trB {
+        return matmulNTFloat
+    }
+    return matmulNNFloat
+}
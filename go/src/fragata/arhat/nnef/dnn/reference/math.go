@@ -141,6 +141,39 @@ func max(x float32, y float32) float32 {
     }
 }
 
+func absInt(x int) int {
+    if x < 0 {
+        return -x
+    }
+    return x
+}
+
+func signInt(x int) int {
+    if x > 0 {
+        return 1
+    }
+    if x < 0 {
+        return -1
+    }
+    return 0
+}
+
+func minInt(x int, y int) int {
+    if x <= y {
+        return x
+    } else {
+        return y
+    }
+}
+
+func maxInt(x int, y int) int {
+    if x >= y {
+        return x
+    } else {
+        return y
+    }
+}
+
 //
 //    Diagnostic functions
 //
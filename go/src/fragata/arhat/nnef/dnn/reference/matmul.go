@@ -24,21 +24,34 @@ package reference
 
 // interface
 
-func Matmul(trA bool, trB bool, a *Tensor, b *Tensor, c *Tensor) error {
-    matmulLoopFloat(trA, trB, a, b, c)
+func Matmul(workers int, trA bool, trB bool, a *Tensor, b *Tensor, c *Tensor) error {
+    matmulLoopFloat(workers, trA, trB, a, b, c)
     return nil
 }
 
-func Linear(input *Tensor, filter *Tensor, bias *Tensor, output *Tensor) error {
-    linearFloat(input, filter, bias, output)
+func Linear(workers int, input *Tensor, filter *Tensor, bias *Tensor, output *Tensor) error {
+    linearFloat(workers, input, filter, bias, output)
     return nil
 }
 
 // implementation
 
-type matmulKernelFloat func(m int, n int, k int, a []float32, b []float32, c []float32)
+// kernels compute rows [i0, i1) of m x n result
 
-func matmulLoopFloat(trA bool, trB bool, a *Tensor, b *Tensor, c *Tensor) {
+type matmulKernelFloat func(
+        m int, 
+        n int, 
+        k int, 
+        i0 int, 
+        i1 int, 
+        a []float32, 
+        b []float32, 
+        c []float32)
+
+// Batch dimensions are broadcast, operand of lower rank is treated
+// as having leading singleton dimensions.
+
+func matmulLoopFloat(workers int, trA bool, trB bool, a *Tensor, b *Tensor, c *Tensor) {
     aData := a.FloatData()
     bData := b.FloatData()
     cData := c.FloatData()
@@ -46,26 +59,76 @@ func matmulLoopFloat(trA bool, trB bool, a *Tensor, b *Tensor, c *Tensor) {
     bShape := b.shape
     cShape := c.shape
     fillFloat(cData, float32(0.0))
-    offset := c.rank - 2
-    dA := aShape[offset] * aShape[offset+1]
-    dB := bShape[offset] * bShape[offset+1]
-    dC := cShape[offset] * cShape[offset+1]
-    m := cShape[offset]
-    n := cShape[offset+1]
+    batchRank := c.rank - 2
+    aBatch := matmulBatchShape(aShape, batchRank)
+    bBatch := matmulBatchShape(bShape, batchRank)
+    dA := aShape[a.rank-2] * aShape[a.rank-1]
+    dB := bShape[b.rank-2] * bShape[b.rank-1]
+    dC := cShape[batchRank] * cShape[batchRank+1]
+    m := cShape[batchRank]
+    n := cShape[batchRank+1]
     var k int
     if trA {
-        k = aShape[offset]
+        k = aShape[a.rank-2]
     } else {
-        k = aShape[offset+1]
+        k = aShape[a.rank-1]
+    }
+    kernel := getMatmulKernelFloat(trA, trB, m, n, k)
+    batchVolume := volumeOf(cShape[:batchRank])
+    aOffsets := make([]int, batchVolume)
+    bOffsets := make([]int, batchVolume)
+    var aIndex, bIndex [ndMaxRank]int
+    var loop NdLoop
+    i := 0
+    for loop.Start(cShape[:batchRank]); loop.Test(); loop.Next() {
+        cIndex := loop.Index()
+        broadcastIndex(cIndex, aBatch, aIndex[:batchRank])
+        broadcastIndex(cIndex, bBatch, bIndex[:batchRank])
+        aOffsets[i] = dA * NdOffset(aBatch, aIndex[:batchRank])
+        bOffsets[i] = dB * NdOffset(bBatch, bIndex[:batchRank])
+        i++
+    }
+    // tasks are rows of all batch items, each row is computed entirely by one task
+    parallelFor(workers, batchVolume * m, func(begin int, end int) {
+        for r := begin; r < end; {
+            i := r / m
+            i0 := r % m
+            i1 := minInt(m, i0+end-r)
+            kernel(m, n, k, i0, i1, aData[aOffsets[i]:], bData[bOffsets[i]:], cData[dC*i:])
+            r += i1 - i0
+        }
+    })
+}
+
+func matmulBatchShape(shape []int, batchRank int) []int {
+    size := len(shape) - 2
+    assert(size <= batchRank)
+    result := make([]int, batchRank)
+    lead := batchRank - size
+    for i := 0; i < lead; i++ {
+        result[i] = 1
     }
-    kernel := getMatmulKernelFloat(trA, trB)
-    v := volumeOf(cShape[:offset])
-    for i := 0; i < v; i++ {
-        kernel(m, n, k, aData[dA*i:], bData[dB*i:], cData[dC*i:])
+    copy(result[lead:], shape[:size])
+    return result
+}
+
+// maps index into broadcast result to index into operand of given shape
+
+func broadcastIndex(index []int, shape []int, result []int) {
+    rank := len(shape)
+    for i := 0; i < rank; i++ {
+        if shape[i] == 1 {
+            result[i] = 0
+        } else {
+            result[i] = index[i]
+        }
     }
 }
 
-func getMatmulKernelFloat(trA bool, trB bool) matmulKernelFloat {
+func getMatmulKernelFloat(trA bool, trB bool, m int, n int, k int) matmulKernelFloat {
+    if useGemm(m, n, k) {
+        return getMatmulGemmKernelFloat(trA, trB)
+    }
     if trA {
         if trB {
             return matmulTTFloat
@@ -81,14 +144,31 @@ func getMatmulKernelFloat(trA bool, trB bool) matmulKernelFloat {
     }
 }
 
-func linearFloat(input *Tensor, filter *Tensor, bias *Tensor, output *Tensor) {
+// blocked kernel yields same results as plain ones and is faster for large operands
+
+func getMatmulGemmKernelFloat(trA bool, trB bool) matmulKernelFloat {
+    return func(m int, n int, k int, i0 int, i1 int, a []float32, b []float32, c []float32) {
+        lda := k
+        if trA {
+            lda = m
+        }
+        ldb := n
+        if trB {
+            ldb = k
+        }
+        gemmFloat(trA, trB, m, n, k, i0, i1, a, lda, b, ldb, c, n)
+    }
+}
+
+func linearFloat(workers int, input *Tensor, filter *Tensor, bias *Tensor, output *Tensor) {
     inputData := input.FloatData()
     filterData := filter.FloatData()
     biasData := bias.FloatData()
     outputData := output.FloatData()
-    m := output.shape[0]
-    n := output.shape[1]
-    k := input.shape[1]
+    // leading dimensions of input are flattened
+    k := input.shape[input.rank-1]
+    n := filter.shape[0]
+    m := volumeOf(input.shape[:input.rank-1])
     if bias.volume == 1 {
         fillFloat(outputData, biasData[0])
     } else {
@@ -96,13 +176,24 @@ func linearFloat(input *Tensor, filter *Tensor, bias *Tensor, output *Tensor) {
             copy(outputData[i*n:(i+1)*n], biasData)
         }
     }
-    matmulNTFloat(m, n, k, inputData, filterData, outputData)
+    kernel := getMatmulKernelFloat(false, true, m, n, k)
+    parallelFor(workers, m, func(begin int, end int) {
+        kernel(m, n, k, begin, end, inputData, filterData, outputData)
+    })
 }
 
 // kernels
 
-func matmulNNFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
-    for i := 0; i < m; i++ {
+func matmulNNFloat(
+        m int, 
+        n int, 
+        k int, 
+        i0 int, 
+        i1 int, 
+        a []float32, 
+        b []float32, 
+        c []float32) {
+    for i := i0; i < i1; i++ {
         for j := 0; j < n; j++ {
             ij := i * n + j
             x := c[ij]
@@ -114,8 +205,16 @@ func matmulNNFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
     }
 }
 
-func matmulNTFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
-    for i := 0; i < m; i++ {
+func matmulNTFloat(
+        m int, 
+        n int, 
+        k int, 
+        i0 int, 
+        i1 int, 
+        a []float32, 
+        b []float32, 
+        c []float32) {
+    for i := i0; i < i1; i++ {
         for j := 0; j < n; j++ {
             ij := i * n + j
             x := c[ij]
@@ -127,8 +226,16 @@ func matmulNTFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
     }
 }
 
-func matmulTNFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
-    for i := 0; i < m; i++ {
+func matmulTNFloat(
+        m int, 
+        n int, 
+        k int, 
+        i0 int, 
+        i1 int, 
+        a []float32, 
+        b []float32, 
+        c []float32) {
+    for i := i0; i < i1; i++ {
         for j := 0; j < n; j++ {
             ij := i * n + j
             x := c[ij]
@@ -140,8 +247,16 @@ func matmulTNFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
     }
 }
 
-func matmulTTFloat(m int, n int, k int, a []float32, b []float32, c []float32) {
-    for i := 0; i < m; i++ {
+func matmulTTFloat(
+        m int, 
+        n int, 
+        k int, 
+        i0 int, 
+        i1 int, 
+        a []float32, 
+        b []float32, 
+        c []float32) {
+    for i := i0; i < i1; i++ {
         for j := 0; j < n; j++ {
             ij := i * n + j
             x := c[ij]
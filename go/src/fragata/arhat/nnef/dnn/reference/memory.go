@@ -27,6 +27,7 @@ import "fragata/arhat/nnef/dnn/api"
 // interface
 
 func Fill(tensor *Tensor, data interface{}) error {
+    tensor.version++
     switch tensor.dtype {
     case api.DtypeBool:
         x := tensor.BoolData()
@@ -85,6 +86,7 @@ func Read(tensor *Tensor, data interface{}) error {
 }
 
 func Copy(input *Tensor, output *Tensor) error {
+    output.version++
     switch output.dtype {
     case api.DtypeBool:
         copy(output.BoolData(), input.BoolData())
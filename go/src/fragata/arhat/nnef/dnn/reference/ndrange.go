@@ -1,6 +1,8 @@
 
 package reference
 
+import "fragata/arhat/nnef/dnn/api"
+
 //
 //    NdLoop2
 //
@@ -229,3 +231,76 @@ func NdOffset5(shape []int, index []int) int {
     return offset
 }
 
+
+//
+//    NdLoop
+//
+
+// General iterator for any rank up to ndMaxRank.
+// Unrolled NdLoop2..NdLoop5 remain for kernels specialized by rank.
+
+const ndMaxRank = api.MaxRank
+
+type NdLoop struct {
+    rank int
+    shape [ndMaxRank]int
+    index [ndMaxRank]int
+    test bool
+}
+
+func(l *NdLoop) Start(shape []int) {
+    rank := len(shape)
+    assert(rank <= ndMaxRank)
+    l.rank = rank
+    test := true
+    for i := 0; i < rank; i++ {
+        l.shape[i] = shape[i]
+        l.index[i] = 0
+        if shape[i] == 0 {
+            test = false
+        }
+    }
+    l.test = test
+}
+
+// Starts iteration at element with given row-major offset
+
+func(l *NdLoop) StartAt(shape []int, offset int) {
+    l.Start(shape)
+    for i := l.rank - 1; i >= 0 && offset != 0; i-- {
+        dim := l.shape[i]
+        l.index[i] = offset % dim
+        offset /= dim
+    }
+    if offset != 0 {
+        l.test = false
+    }
+}
+
+func(l *NdLoop) Test() bool {
+    return l.test
+}
+
+func(l *NdLoop) Next() {
+    for i := l.rank - 1; i >= 0; i-- {
+        l.index[i]++
+        if l.index[i] < l.shape[i] {
+            return
+        }
+        l.index[i] = 0
+    }
+    l.test = false
+}
+
+func(l *NdLoop) Index() []int {
+    return l.index[:l.rank]
+}
+
+func NdOffset(shape []int, index []int) int {
+    rank := len(shape)
+    offset := 0
+    for i := 0; i < rank; i++ {
+        offset = offset * shape[i] + index[i]
+    }
+    return offset
+}
@@ -0,0 +1,289 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import "fragata/arhat/nnef/dnn/api"
+
+// interface
+
+func LocalNorm(
+        op api.LocalNormOp,
+        input *Tensor,
+        output *Tensor,
+        size []int,
+        alpha float32,
+        beta float32,
+        bias float32,
+        epsilon float32) error {
+    x := input.FloatData()
+    y := output.FloatData()
+    shape := input.shape
+    n := input.volume
+    switch op {
+    case api.OpLocalResponseNorm:
+        sqrFloat(x, y)
+        localAverageFloat(y, shape, size)
+        for i := 0; i < n; i++ {
+            y[i] = x[i] / pow(bias + alpha * y[i], beta)
+        }
+    case api.OpLocalMeanNorm:
+        copy(y, x)
+        localAverageFloat(y, shape, size)
+        for i := 0; i < n; i++ {
+            y[i] = x[i] - y[i]
+        }
+    case api.OpLocalVarianceNorm:
+        sqrFloat(x, y)
+        localAverageFloat(y, shape, size)
+        for i := 0; i < n; i++ {
+            y[i] = x[i] / max(sqrt(y[i]) + bias, epsilon)
+        }
+    case api.OpLocalContrastNorm:
+        copy(y, x)
+        localAverageFloat(y, shape, size)
+        for i := 0; i < n; i++ {
+            y[i] = x[i] - y[i]
+        }
+        sigma := make([]float32, n)
+        sqrFloat(y, sigma)
+        localAverageFloat(sigma, shape, size)
+        for i := 0; i < n; i++ {
+            y[i] /= max(sqrt(sigma[i]) + bias, epsilon)
+        }
+    default:
+        assert(false)
+    }
+    return nil
+}
+
+func Norm(
+        op api.NormOp, 
+        input *Tensor, 
+        output *Tensor, 
+        axes []int, 
+        bias float32, 
+        epsilon float32) error {
+    rank := input.rank
+    shape := cloneShape(input.shape)
+    for _, axis := range axes {
+        shape[axis] = 1
+    }
+    sigma, err := NewTensor(api.DtypeFloat, shape)
+    if err != nil {
+        return err
+    }
+    var xv, sv TensorView
+    xv.Init(input, rank)
+    sv.Init(sigma, rank)
+    s := sigma.FloatData()
+    switch op {
+    case api.OpL1Norm:
+        reduceLoop(0, &xv, &sv, reduceAbsSumFloat)
+        for i := range s {
+            s[i] = max(s[i] + bias, epsilon)
+        }
+    case api.OpL2Norm:
+        reduceLoop(0, &xv, &sv, reduceSqrSumFloat)
+        for i := range s {
+            s[i] = max(sqrt(s[i]) + bias, epsilon)
+        }
+    default:
+        assert(false)
+    }
+    return Binary(api.OpDiv, input, sigma, output)
+}
+
+func BatchNorm(
+        input *Tensor,
+        mean *Tensor,
+        variance *Tensor,
+        offset *Tensor,
+        scale *Tensor,
+        output *Tensor,
+        epsilon float32) error {
+    var xv, mv, vv, ov, sv, yv TensorView
+    rank := output.rank
+    xv.Init(input, rank)
+    mv.Init(mean, rank)
+    vv.Init(variance, rank)
+    ov.Init(offset, rank)
+    sv.Init(scale, rank)
+    yv.Init(output, rank)
+    batchNormLoopFloat(0, &xv, &mv, &vv, &ov, &sv, &yv, epsilon)
+    return nil
+}
+
+// implementation
+
+// Replaces data with its average over a window of the given size centered
+// as with automatic 'same' padding; out-of-range items count as zeros.
+// The box filter is separable, so each axis is processed in turn in place.
+func localAverageFloat(data []float32, shape []int, size []int) {
+    rank := len(shape)
+    for axis := 0; axis < rank; axis++ {
+        k := size[axis]
+        if k == 1 {
+            continue
+        }
+        batch := volumeOf(shape[:axis])
+        n := shape[axis]
+        inner := volumeOf(shape[axis+1:])
+        padding := (k - 1) / 2
+        line := make([]float32, n)
+        prefix := make([]float64, n+1)
+        for b := 0; b < batch; b++ {
+            for c := 0; c < inner; c++ {
+                offset := b * n * inner + c
+                for i := 0; i < n; i++ {
+                    line[i] = data[offset+i*inner]
+                }
+                for i := 0; i < n; i++ {
+                    prefix[i+1] = prefix[i] + float64(line[i])
+                }
+                for i := 0; i < n; i++ {
+                    lo := clipInt(i - padding, 0, n)
+                    hi := clipInt(i - padding + k, 0, n)
+                    data[offset+i*inner] = float32(prefix[hi] - prefix[lo])
+                }
+            }
+        }
+    }
+    scaleFloat(data, float32(1.0) / float32(volumeOf(size)))
+}
+
+func sqrFloat(x []float32, y []float32) {
+    n := len(x)
+    for i := 0; i < n; i++ {
+        y[i] = x[i] * x[i]
+    }
+}
+
+func reduceAbsSumFloat(
+        n int,
+        ax interface{},
+        px int,
+        dx int,
+        ay interface{},
+        py int,
+        dy int) {
+    x := ax.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        y[i*dy+py] += abs(x[i*dx+px])
+    }
+}
+
+func reduceSqrSumFloat(
+        n int,
+        ax interface{},
+        px int,
+        dx int,
+        ay interface{},
+        py int,
+        dy int) {
+    x := ax.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        t := x[i*dx+px]
+        y[i*dy+py] += t * t
+    }
+}
+
+func batchNormLoopFloat(
+        level int,
+        x *TensorView,
+        m *TensorView,
+        v *TensorView,
+        o *TensorView,
+        s *TensorView,
+        y *TensorView,
+        epsilon float32) {
+    xVolume := x.volume[level]
+    mVolume := m.volume[level]
+    vVolume := v.volume[level]
+    oVolume := o.volume[level]
+    sVolume := s.volume[level]
+    yVolume := y.volume[level]
+    if (xVolume == yVolume || xVolume == 1) &&
+            (mVolume == yVolume || mVolume == 1) &&
+            (vVolume == yVolume || vVolume == 1) &&
+            (oVolume == yVolume || oVolume == 1) &&
+            (sVolume == yVolume || sVolume == 1) {
+        batchNormFloat(
+            yVolume,
+            x.data.([]float32)[x.offset[level]:],
+            btoi(xVolume == yVolume),
+            m.data.([]float32)[m.offset[level]:],
+            btoi(mVolume == yVolume),
+            v.data.([]float32)[v.offset[level]:],
+            btoi(vVolume == yVolume),
+            o.data.([]float32)[o.offset[level]:],
+            btoi(oVolume == yVolume),
+            s.data.([]float32)[s.offset[level]:],
+            btoi(sVolume == yVolume),
+            y.data.([]float32)[y.offset[level]:],
+            epsilon)
+    } else {
+        assert(level + 1 < y.rank)
+        x.Start(level)
+        m.Start(level)
+        v.Start(level)
+        o.Start(level)
+        s.Start(level)
+        y.Start(level)
+        n := y.shape[level]
+        for i := 0; i < n; i++ {
+            batchNormLoopFloat(level+1, x, m, v, o, s, y, epsilon) 
+            x.Next(level)
+            m.Next(level)
+            v.Next(level)
+            o.Next(level)
+            s.Next(level)
+            y.Next(level)
+        }
+    }
+}
+
+// kernels
+
+func batchNormFloat(
+        n int,
+        x []float32,
+        dx int,
+        m []float32,
+        dm int,
+        v []float32,
+        dv int,
+        o []float32,
+        do int,
+        s []float32,
+        ds int,
+        y []float32,
+        epsilon float32) {
+    if dm == 0 && dv == 0 && do == 0 && ds == 0 {
+        // common case: per-channel parameters over a contiguous run
+        a := s[0] * rsqrt(v[0] + epsilon)
+        b := o[0] - a * m[0]
+        for i := 0; i < n; i++ {
+            y[i] = a * x[i*dx] + b
+        }
+        return
+    }
+    for i := 0; i < n; i++ {
+        y[i] = o[i*do] + s[i*ds] * (x[i*dx] - m[i*dm]) * rsqrt(v[i*dv] + epsilon)
+    }
+}
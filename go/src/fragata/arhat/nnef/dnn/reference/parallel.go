@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import (
+    "runtime"
+    "sync"
+)
+
+//
+//    Parallel loops
+//
+
+// Number of workers used when none is specified
+
+func defaultWorkers() int {
+    return runtime.NumCPU()
+}
+
+// Splits range [0, count) into contiguous chunks processed by up to
+// workers goroutines. Each index belongs to exactly one chunk, so bodies
+// that write disjoint outputs per index yield results independent
+// of the number of workers.
+
+func parallelFor(workers int, count int, body func(begin int, end int)) {
+    if workers > count {
+        workers = count
+    }
+    if workers <= 1 {
+        body(0, count)
+        return
+    }
+    chunk := (count + workers - 1) / workers
+    var wg sync.WaitGroup
+    for begin := 0; begin < count; begin += chunk {
+        end := minInt(begin+chunk, count)
+        wg.Add(1)
+        go func(begin int, end int) {
+            defer wg.Done()
+            body(begin, end)
+        }(begin, end)
+    }
+    wg.Wait()
+}
+
@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+import (
+    "math/rand"
+    "sync"
+    "testing"
+    "fragata/arhat/nnef/dnn/api"
+)
+
+//
+//    Helpers
+//
+
+func randomTensor(shape []int, seed int64) *Tensor {
+    t, _ := NewTensor(api.DtypeFloat, shape)
+    r := rand.New(rand.NewSource(seed))
+    data := t.FloatData()
+    for i := range data {
+        data[i] = r.Float32() * 2 - 1
+    }
+    return t
+}
+
+func zeroTensor(shape []int) *Tensor {
+    t, _ := NewTensor(api.DtypeFloat, shape)
+    return t
+}
+
+func checkIdentical(t *testing.T, name string, x *Tensor, y *Tensor) {
+    t.Helper()
+    xData := x.FloatData()
+    yData := y.FloatData()
+    for i := range xData {
+        if xData[i] != yData[i] {
+            t.Fatalf("%s: item %d differs: %g != %g", name, i, xData[i], yData[i])
+        }
+    }
+}
+
+//
+//    Tests
+//
+
+func TestParallelForCoversRange(t *testing.T) {
+    for _, count := range []int{0, 1, 5, 16, 17, 100} {
+        for _, workers := range []int{1, 2, 3, 4, 8, 200} {
+            visits := make([]int, count)
+            var mutex sync.Mutex
+            parallelFor(workers, count, func(begin int, end int) {
+                mutex.Lock()
+                defer mutex.Unlock()
+                for i := begin; i < end; i++ {
+                    visits[i]++
+                }
+            })
+            for i, n := range visits {
+                if n != 1 {
+                    t.Fatalf("count %d, workers %d: index %d visited %d times", count, workers, i, n)
+                }
+            }
+        }
+    }
+}
+
+// Results must not depend on number of workers; shapes cover
+// direct, GEMM and Winograd convolution paths
+
+func TestParallelConv(t *testing.T) {
+    tests := []struct {
+        name string
+        transposed bool
+        input []int
+        filter []int
+        output []int
+        padding []int
+        stride []int
+    }{
+        {"direct", false, []int{2, 3, 9, 9}, []int{4, 3, 3, 3}, []int{2, 4, 5, 5}, []int{1, 1}, []int{2, 2}},
+        {"gemm", false, []int{2, 16, 12, 12}, []int{16, 16, 3, 3}, []int{2, 16, 6, 6}, []int{1, 1}, []int{2, 2}},
+        {"winograd", false, []int{2, 8, 18, 18}, []int{8, 8, 3, 3}, []int{2, 8, 18, 18}, []int{1, 1}, []int{1, 1}},
+        {"deconv", true, []int{2, 4, 5, 5}, []int{4, 3, 3, 3}, []int{2, 3, 9, 9}, []int{1, 1}, []int{2, 2}},
+        {"deconv gemm", true, []int{2, 16, 6, 6}, []int{16, 16, 3, 3}, []int{2, 16, 12, 12}, []int{1, 1}, []int{2, 2}},
+    }
+    for _, test := range tests {
+        dilation := []int{1, 1}
+        var results [2]*Tensor
+        for i, workers := range []int{1, 4} {
+            x := randomTensor(test.input, 1)
+            filter := randomTensor(test.filter, 2)
+            result := zeroTensor(test.output)
+            if test.transposed {
+                bias := randomTensor([]int{1, test.filter[1]}, 3)
+                Conv(workers, true, result, filter, bias, x, test.padding, test.stride, dilation, api.BorderConstant)
+            } else {
+                bias := randomTensor([]int{1, test.filter[0]}, 3)
+                Conv(workers, false, x, filter, bias, result, test.padding, test.stride, dilation, api.BorderConstant)
+            }
+            results[i] = result
+        }
+        checkIdentical(t, test.name, results[0], results[1])
+    }
+}
+
+func TestParallelMatmul(t *testing.T) {
+    tests := []struct {
+        a []int
+        b []int
+        c []int
+    }{
+        {[]int{3, 5, 7}, []int{3, 7, 4}, []int{3, 5, 4}},
+        {[]int{2, 33, 40}, []int{40, 24}, []int{2, 33, 24}},
+    }
+    for _, test := range tests {
+        a := randomTensor(test.a, 1)
+        b := randomTensor(test.b, 2)
+        c1 := zeroTensor(test.c)
+        c4 := zeroTensor(test.c)
+        Matmul(1, false, false, a, b, c1)
+        Matmul(4, false, false, a, b, c4)
+        checkIdentical(t, "matmul", c1, c4)
+    }
+}
+
+func TestParallelPool(t *testing.T) {
+    size := []int{1, 1, 3, 3}
+    padding := []int{0, 0, 1, 1}
+    stride := []int{1, 1, 2, 2}
+    dilation := []int{1, 1, 1, 1}
+    for _, op := range []api.PoolOp{api.OpSumPool, api.OpAvgPool, api.OpMaxPool} {
+        input := randomTensor([]int{2, 3, 9, 9}, 1)
+        y1 := zeroTensor([]int{2, 3, 5, 5})
+        y4 := zeroTensor([]int{2, 3, 5, 5})
+        Pool(1, op, false, input, y1, size, padding, stride, dilation, api.BorderIgnore)
+        Pool(4, op, false, input, y4, size, padding, stride, dilation, api.BorderIgnore)
+        checkIdentical(t, "pool", y1, y4)
+        output := randomTensor([]int{2, 3, 5, 5}, 2)
+        x1 := zeroTensor([]int{2, 3, 9, 9})
+        x4 := zeroTensor([]int{2, 3, 9, 9})
+        Pool(1, op, true, x1, output, size, padding, stride, dilation, api.BorderIgnore)
+        Pool(4, op, true, x4, output, size, padding, stride, dilation, api.BorderIgnore)
+        checkIdentical(t, "transposed pool", x1, x4)
+    }
+}
@@ -27,6 +27,7 @@ import "fragata/arhat/nnef/dnn/api"
 // interface
 
 func Pool(
+        workers int,
         op api.PoolOp,
         transposed bool,
         input *Tensor,
@@ -35,25 +36,31 @@ func Pool(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) error {
+        border api.Border) error {
     if transposed {
         poolInit(op, input)
     } else {
         poolInit(op, output)
     }
-    kernel := getPoolKernelFloat(transposed, input.rank, op)
-    kernel(
-        input.FloatData(),
-        output.FloatData(),
-        input.shape,
-        output.shape,
-        size,
-        padding,
-        stride,
-        dilation,
-        includeBorder)
+    kernel := getPoolKernelFloat(transposed, op)
+    run := func(begin int, end int) {
+        kernel(
+            input.FloatData(),
+            output.FloatData(),
+            input.shape,
+            output.shape,
+            size,
+            padding,
+            stride,
+            dilation,
+            border,
+            begin,
+            end)
+    }
+    poolParallelFor(workers, transposed, output.volume, run)
     if op == api.OpAvgPool {
         poolAverage(
+            workers,
             transposed, 
             input, 
             output, 
@@ -61,7 +68,7 @@ func Pool(
             padding,
             stride,
             dilation,
-            includeBorder)
+            border)
     } 
     return nil
 }
@@ -80,6 +87,7 @@ func poolInit(op api.PoolOp, tensor *Tensor) {
 }
 
 func poolAverage(
+        workers int,
         transposed bool,
         input *Tensor,
         output *Tensor,
@@ -87,8 +95,9 @@ func poolAverage(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) {
-    if includeBorder {
+        border api.Border) {
+    // all positions of the window supply values unless border is ignored
+    if border != api.BorderIgnore {
         if transposed {
             poolAverageVolume(input, size)
         } else {
@@ -96,6 +105,7 @@ func poolAverage(
         }
     } else {
         poolAverageArea(
+            workers,
             transposed,
             input,
             output,
@@ -112,6 +122,7 @@ func poolAverageVolume(tensor *Tensor, size []int) {
 }
 
 func poolAverageArea(
+        workers int,
         transposed bool,
         input *Tensor,
         output *Tensor,
@@ -129,15 +140,20 @@ func poolAverageArea(
         resultVolume = output.volume
     }
     counterData := make([]float32, resultVolume)
-    kernel := getPoolAreaKernel(transposed, input.rank)
-    kernel(
-        counterData,
-        input.shape,
-        output.shape,
-        size,
-        padding,
-        stride,
-        dilation)
+    kernel := getPoolAreaKernel(transposed)
+    run := func(begin int, end int) {
+        kernel(
+            counterData,
+            input.shape,
+            output.shape,
+            size,
+            padding,
+            stride,
+            dilation,
+            begin,
+            end)
+    }
+    poolParallelFor(workers, transposed, output.volume, run)
     for i := 0; i < resultVolume; i++ {
         resultData[i] /= counterData[i]
     }
@@ -152,55 +168,24 @@ type poolKernelFloat func(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool)
-
-var poolKernelsNSumFloat = [...]poolKernelFloat {
-    poolCoreN1SumFloat,
-    poolCoreN2SumFloat,
-    poolCoreN3SumFloat,
-    poolCoreN4SumFloat,
-    poolCoreN5SumFloat,
-}
-
-var poolKernelsNMaxFloat = [...]poolKernelFloat {
-    poolCoreN1MaxFloat,
-    poolCoreN2MaxFloat,
-    poolCoreN3MaxFloat,
-    poolCoreN4MaxFloat,
-    poolCoreN5MaxFloat,
-}
-
-var poolKernelsTSumFloat = [...]poolKernelFloat {
-    poolCoreT1SumFloat,
-    poolCoreT2SumFloat,
-    poolCoreT3SumFloat,
-    poolCoreT4SumFloat,
-    poolCoreT5SumFloat,
-}
+        border api.Border,
+        begin int,
+        end int)
 
-var poolKernelsTMaxFloat = [...]poolKernelFloat {
-    poolCoreT1MaxFloat,
-    poolCoreT2MaxFloat,
-    poolCoreT3MaxFloat,
-    poolCoreT4MaxFloat,
-    poolCoreT5MaxFloat,
-}
-
-func getPoolKernelFloat(transposed bool, rank int, op api.PoolOp) poolKernelFloat {
-    assert(rank >= 1 && rank <= 5)
+func getPoolKernelFloat(transposed bool, op api.PoolOp) poolKernelFloat {
     if transposed {
         switch op {
         case api.OpSumPool, api.OpAvgPool:
-            return poolKernelsTSumFloat[rank-1]
+            return poolCoreTSumFloat
         case api.OpMaxPool:
-            return poolKernelsTMaxFloat[rank-1]
+            return poolCoreTMaxFloat
         }
     } else {
         switch op {
         case api.OpSumPool, api.OpAvgPool:
-            return poolKernelsNSumFloat[rank-1]
+            return poolCoreNSumFloat
         case api.OpMaxPool:
-            return poolKernelsNMaxFloat[rank-1]
+            return poolCoreNMaxFloat
         }
     }
     assert(false)
@@ -214,156 +199,46 @@ type poolAreaKernel func(
         size []int,
         padding []int,
         stride []int,
-        dilation []int)
-
-var poolAreaKernelsN = [...]poolAreaKernel{
-    poolAreaN1,
-    poolAreaN2,
-    poolAreaN3,
-    poolAreaN4,
-    poolAreaN5,
-}
-
-var poolAreaKernelsT = [...]poolAreaKernel{
-    poolAreaT1,
-    poolAreaT2,
-    poolAreaT3,
-    poolAreaT4,
-    poolAreaT5,
-}
+        dilation []int,
+        begin int,
+        end int)
 
-func getPoolAreaKernel(transposed bool, rank int) poolAreaKernel {
-    assert(rank >= 1 && rank <= 5)
+func getPoolAreaKernel(transposed bool) poolAreaKernel {
     if transposed {
-        return poolAreaKernelsT[rank-1]
+        return poolAreaT
     } else {
-        return poolAreaKernelsN[rank-1]    
+        return poolAreaN
     }    
 }
 
-// kernels (normal, sum)
+// Kernels process outputs with offsets in [begin, end). Windows of
+// distinct outputs may overlap in input, therefore transposed kernels
+// that accumulate into input are run serially.
 
-func poolCoreN1SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                outputData[outputIndex] += inputData[inputIndex]
-            }
-            // ignore includeBorder
-        }
+func poolParallelFor(workers int, transposed bool, count int, body func(begin int, end int)) {
+    if transposed {
+        body(0, count)
+    } else {
+        parallelFor(workers, count, body)
     }
 }
 
-func poolCoreN2SumFloat(
-        inputData []float32,
-        outputData []float32, 
-        inputShape []int,
-        outputShape []int,
-        size []int,
+func poolWindowIndex(
+        inputIndex []int,
+        outputIndex []int,
+        kernelIndex []int,
         padding []int,
         stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                inputOffset := NdOffset2(inputShape, inputIndex[:])
-                outputData[outputOffset] += inputData[inputOffset]
-            }
-            // ignore includeBorder
-        }
-    }
-}
-
-func poolCoreN3SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                inputOffset := NdOffset3(inputShape, inputIndex[:])
-                outputData[outputOffset] += inputData[inputOffset]
-            }
-            // ignore includeBorder
-        }
+        dilation []int) {
+    rank := len(inputIndex)
+    for i := 0; i < rank; i++ {
+        inputIndex[i] = outputIndex[i] * stride[i] + kernelIndex[i] * dilation[i] - padding[i]
     }
 }
 
-func poolCoreN4SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                inputOffset := NdOffset4(inputShape, inputIndex[:])
-                outputData[outputOffset] += inputData[inputOffset]
-            }
-            // ignore includeBorder
-        }
-    }
-}
+// kernels (normal)
 
-func poolCoreN5SumFloat(
+func poolCoreNSumFloat(
         inputData []float32,
         outputData []float32,
         inputShape []int,
@@ -372,93 +247,28 @@ func poolCoreN5SumFloat(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        border api.Border,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
-        outputOffset := NdOffset5(outputShape, outputIndex)
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
-                inputOffset := NdOffset5(inputShape, inputIndex[:])
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
                 outputData[outputOffset] += inputData[inputOffset]
             }
-            // ignore includeBorder
-        }
-    }
-}
-
-// kernels (normal, max)
-
-func poolCoreN1MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                outputData[outputIndex] = max(outputData[outputIndex], inputData[inputIndex])
-            } else if includeBorder {
-                outputData[outputIndex] = max(outputData[outputIndex], float32(0.0))
-            }
-        }
-    }
-}
-
-func poolCoreN2MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                inputOffset := NdOffset2(inputShape, inputIndex[:])
-                outputData[outputOffset] = max(outputData[outputOffset], inputData[inputOffset])
-            } else if includeBorder {
-                outputData[outputOffset] = max(outputData[outputOffset], float32(0.0))
-            }
         }
+        outputLoop.Next()
     }
 }
 
-func poolCoreN3MaxFloat(
+func poolCoreNMaxFloat(
         inputData []float32,
         outputData []float32,
         inputShape []int,
@@ -467,64 +277,32 @@ func poolCoreN3MaxFloat(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        border api.Border,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
-        outputOffset := NdOffset3(outputShape, outputIndex)
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                inputOffset := NdOffset3(inputShape, inputIndex[:])
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
                 outputData[outputOffset] = max(outputData[outputOffset], inputData[inputOffset])
-            } else if includeBorder {
+            } else if border == api.BorderConstant {
                 outputData[outputOffset] = max(outputData[outputOffset], float32(0.0))
             }
         }
+        outputLoop.Next()
     }
 }
 
-func poolCoreN4MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                inputOffset := NdOffset4(inputShape, inputIndex[:])
-                outputData[outputOffset] = max(outputData[outputOffset], inputData[inputOffset])
-            } else if includeBorder {
-                outputData[outputOffset] = max(outputData[outputOffset], float32(0.0))
-            }
-        }
-    }
-}
+// kernels (transposed)
 
-func poolCoreN5MaxFloat(
+func poolCoreTSumFloat(
         inputData []float32,
         outputData []float32,
         inputShape []int,
@@ -533,123 +311,28 @@ func poolCoreN5MaxFloat(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        border api.Border,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
-        outputOffset := NdOffset5(outputShape, outputIndex)
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
-                inputOffset := NdOffset5(inputShape, inputIndex[:])
-                outputData[outputOffset] = max(outputData[outputOffset], inputData[inputOffset])
-            } else if includeBorder {
-                outputData[outputOffset] = max(outputData[outputOffset], float32(0.0))
-            }
-        }
-    }
-}
-
-// kernels (transposed, sum)
-
-func poolCoreT1SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                inputData[inputIndex] += outputData[outputIndex]
-            }
-            // ignore includeBorder
-        }
-    }
-}
-
-func poolCoreT2SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                inputOffset := NdOffset2(inputShape, inputIndex[:])
-                inputData[inputOffset] += outputData[outputOffset]
-            }
-            // ignore includeBorder
-        }
-    }
-}
-
-func poolCoreT3SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                inputOffset := NdOffset3(inputShape, inputIndex[:])
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
                 inputData[inputOffset] += outputData[outputOffset]
             }
-            // ignore includeBorder
         }
+        outputLoop.Next()
     }
 }
 
-func poolCoreT4SumFloat(
+func poolCoreTMaxFloat(
         inputData []float32,
         outputData []float32,
         inputShape []int,
@@ -658,495 +341,80 @@ func poolCoreT4SumFloat(
         padding []int,
         stride []int,
         dilation []int,
-        includeBorder bool) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        border api.Border,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
-        outputOffset := NdOffset4(outputShape, outputIndex)
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                inputOffset := NdOffset4(inputShape, inputIndex[:])
-                inputData[inputOffset] += outputData[outputOffset]
-            }
-            // ignore includeBorder
-        }
-    }
-}
-
-func poolCoreT5SumFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
-                inputOffset := NdOffset5(inputShape, inputIndex[:])
-                inputData[inputOffset] += outputData[outputOffset]
-            }
-            // ignore includeBorder
-        }
-    }
-}
-
-// kernels (transposed, max)
-
-func poolCoreT1MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                inputData[inputIndex] = max(inputData[inputIndex], outputData[outputIndex])
-            }
-            // includeBorder unused
-        }
-    }
-}
-
-func poolCoreT2MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                inputOffset := NdOffset2(inputShape, inputIndex[:])
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
                 inputData[inputOffset] = max(inputData[inputOffset], outputData[outputOffset])
             }
-            // includeBorder unused
         }
+        outputLoop.Next()
     }
 }
 
-func poolCoreT3MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                inputOffset := NdOffset3(inputShape, inputIndex[:])
-                inputData[inputOffset] = max(inputData[inputOffset], outputData[outputOffset])
-            }
-            // includeBorder unused
-        }
-    }
-}
+// kernels (area)
 
-func poolCoreT4MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                inputOffset := NdOffset4(inputShape, inputIndex[:])
-                inputData[inputOffset] = max(inputData[inputOffset], outputData[outputOffset])
-            }
-            // includeBorder unused
-        }
-    }
-}
-
-func poolCoreT5MaxFloat(
-        inputData []float32,
-        outputData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int,
-        includeBorder bool) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
-                inputOffset := NdOffset5(inputShape, inputIndex[:])
-                inputData[inputOffset] = max(inputData[inputOffset], outputData[outputOffset])
-            }
-            // includeBorder unused
-        }
-    }
-}
-
-// kernels: area (normal)
-
-func poolAreaN1(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                counterData[outputIndex] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaN2(
+func poolAreaN(
         counterData []float32,
         inputShape []int,
         outputShape []int,
         size []int,
         padding []int,
         stride []int,
-        dilation []int) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                counterData[outputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaN3(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                counterData[outputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaN4(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                counterData[outputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaN5(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        dilation []int,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
-        outputOffset := NdOffset5(outputShape, outputIndex)
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(api.BorderIgnore, inputIndex[:rank], inputShape) {
                 counterData[outputOffset] += float32(1.0)
             }
         }
+        outputLoop.Next()
     }
 }
 
-// kernels: area (transposed)
-
-func poolAreaT1(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    inputShape0 := inputShape[0]
-    outputShape0 := outputShape[0]
-    size0 := size[0]
-    padding0 := padding[0]
-    stride0 := stride[0]
-    dilation0 := dilation[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        for kernelIndex := 0; kernelIndex < size0; kernelIndex++ {
-            inputIndex := outputIndex * stride0 + kernelIndex * dilation0 - padding0
-            if inputIndex >= 0 && inputIndex < inputShape0 {
-                counterData[inputIndex] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaT2(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [2]int
-    var outputLoop, kernelLoop NdLoop2
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] {
-                inputOffset := NdOffset2(inputShape, inputIndex[:])
-                counterData[inputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaT3(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [3]int
-    var outputLoop, kernelLoop NdLoop3
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] {
-                inputOffset := NdOffset3(inputShape, inputIndex[:])
-                counterData[inputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaT4(
+func poolAreaT(
         counterData []float32,
         inputShape []int,
         outputShape []int,
         size []int,
         padding []int,
         stride []int,
-        dilation []int) {
-    var inputIndex [4]int
-    var outputLoop, kernelLoop NdLoop4
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
-        outputIndex := outputLoop.Index()
-        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
-            kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] {
-                inputOffset := NdOffset4(inputShape, inputIndex[:])
-                counterData[inputOffset] += float32(1.0)
-            }
-        }
-    }
-}
-
-func poolAreaT5(
-        counterData []float32,
-        inputShape []int,
-        outputShape []int,
-        size []int,
-        padding []int,
-        stride []int,
-        dilation []int) {
-    var inputIndex [5]int
-    var outputLoop, kernelLoop NdLoop5
-    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        dilation []int,
+        begin int,
+        end int) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    outputLoop.StartAt(outputShape, begin)
+    for outputOffset := begin; outputOffset < end; outputOffset++ {
         outputIndex := outputLoop.Index()
         for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
             kernelIndex := kernelLoop.Index()
-            inputIndex[0] = outputIndex[0] * stride[0] + kernelIndex[0] * dilation[0] - padding[0]
-            inputIndex[1] = outputIndex[1] * stride[1] + kernelIndex[1] * dilation[1] - padding[1]
-            inputIndex[2] = outputIndex[2] * stride[2] + kernelIndex[2] * dilation[2] - padding[2]
-            inputIndex[3] = outputIndex[3] * stride[3] + kernelIndex[3] * dilation[3] - padding[3]
-            inputIndex[4] = outputIndex[4] * stride[4] + kernelIndex[4] * dilation[4] - padding[4]
-            if inputIndex[0] >= 0 && inputIndex[0] < inputShape[0] &&
-                    inputIndex[1] >= 0 && inputIndex[1] < inputShape[1] &&
-                    inputIndex[2] >= 0 && inputIndex[2] < inputShape[2] &&
-                    inputIndex[3] >= 0 && inputIndex[3] < inputShape[3] &&
-                    inputIndex[4] >= 0 && inputIndex[4] < inputShape[4] {
-                inputOffset := NdOffset5(inputShape, inputIndex[:])
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(api.BorderIgnore, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
                 counterData[inputOffset] += float32(1.0)
             }
         }
+        outputLoop.Next()
     }
 }
-
@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import "fragata/arhat/nnef/dnn/api"
+
+// interface
+
+func MaxPoolWithIndex(
+        input *Tensor,
+        output *Tensor,
+        index *Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    var outputData []float32
+    if output != nil {
+        outputData = output.FloatData()
+    }
+    poolIndexCoreMaxFloat(
+        input.FloatData(),
+        outputData,
+        index.IntData(),
+        input.shape,
+        index.shape,
+        size,
+        padding,
+        stride,
+        dilation,
+        border)
+    return nil
+}
+
+// kernels (max)
+
+// Index values are flattened positions within the pooling window.
+// Output data is optional and may be nil.
+
+func poolIndexCoreMaxFloat(
+        inputData []float32,
+        outputData []float32,
+        indexData []int,
+        inputShape []int,
+        outputShape []int,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) {
+    rank := len(inputShape)
+    var inputIndex [ndMaxRank]int
+    var outputLoop, kernelLoop NdLoop
+    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        outputIndex := outputLoop.Index()
+        outputOffset := NdOffset(outputShape, outputIndex)
+        value := negInf
+        index := 0
+        kernelOffset := 0
+        for kernelLoop.Start(size); kernelLoop.Test(); kernelLoop.Next() {
+            kernelIndex := kernelLoop.Index()
+            poolWindowIndex(inputIndex[:rank], outputIndex, kernelIndex, padding, stride, dilation)
+            if borderIndexN(border, inputIndex[:rank], inputShape) {
+                inputOffset := NdOffset(inputShape, inputIndex[:rank])
+                if inputData[inputOffset] > value {
+                    value = inputData[inputOffset]
+                    index = kernelOffset
+                }
+            } else if border == api.BorderConstant {
+                if float32(0.0) > value {
+                    value = float32(0.0)
+                    index = kernelOffset
+                }
+            }
+            kernelOffset++
+        }
+        if outputData != nil {
+            outputData[outputOffset] = value
+        }
+        indexData[outputOffset] = index
+    }
+}
@@ -0,0 +1,145 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+// interface
+
+func LinearQuantize(x *Tensor, min *Tensor, max *Tensor, y *Tensor, bits int) error {
+    var xv, av, bv, yv TensorView
+    rank := y.rank
+    xv.Init(x, rank)
+    av.Init(min, rank)
+    bv.Init(max, rank)
+    yv.Init(y, rank)
+    r := quantizeRange(bits)
+    kernel := 
+        func(
+                n int,
+                ax interface{},
+                px int,
+                dx int,
+                aa interface{},
+                pa int,
+                da int,
+                ab interface{},
+                pb int,
+                db int,
+                ay interface{},
+                py int,
+                dy int) {
+            linearQuantizeFloat(n, ax, px, dx, aa, pa, da, ab, pb, db, ay, py, dy, r)
+        }
+    // select loop provides broadcasting for three inputs
+    selectLoop(0, &xv, &av, &bv, &yv, kernel)
+    return nil
+}
+
+func LogarithmicQuantize(x *Tensor, max *Tensor, y *Tensor, bits int) error {
+    var xv, bv, yv TensorView
+    rank := y.rank
+    xv.Init(x, rank)
+    bv.Init(max, rank)
+    yv.Init(y, rank)
+    r := quantizeRange(bits)
+    kernel := 
+        func(
+                n int,
+                ax interface{},
+                px int,
+                dx int,
+                ab interface{},
+                pb int,
+                db int,
+                ay interface{},
+                py int,
+                dy int) {
+            logarithmicQuantizeFloat(n, ax, px, dx, ab, pb, db, ay, py, dy, r)
+        }
+    binaryLoop(0, &xv, &bv, &yv, kernel)
+    return nil
+}
+
+// implementation
+
+func quantizeRange(bits int) float32 {
+    return float32((int64(1) << uint(bits)) - 1)
+}
+
+// kernels
+
+// Follows NNEF definition:
+//
+//     z = clamp(x, min, max)
+//     q = round((z - min) / (max - min) * r)
+//     y = q / r * (max - min) + min
+
+func linearQuantizeFloat(
+        n int,
+        ax interface{},
+        px int,
+        dx int,
+        aa interface{},
+        pa int,
+        da int,
+        ab interface{},
+        pb int,
+        db int,
+        ay interface{},
+        py int,
+        dy int,
+        r float32) {
+    x := ax.([]float32)
+    a := aa.([]float32)
+    b := ab.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        lo := a[i*da+pa]
+        hi := b[i*db+pb]
+        z := min(max(x[i*dx+px], lo), hi)
+        q := round((z - lo) / (hi - lo) * r)
+        y[i*dy+py] = q / r * (hi - lo) + lo
+    }
+}
+
+// Follows NNEF definition:
+//
+//     m = ceil(log2(max))
+//     q = round(clamp(log2(abs(x)), m - r, m))
+//     y = sign(x) * 2 ^ q
+
+func logarithmicQuantizeFloat(
+        n int,
+        ax interface{},
+        px int,
+        dx int,
+        ab interface{},
+        pb int,
+        db int,
+        ay interface{},
+        py int,
+        dy int,
+        r float32) {
+    x := ax.([]float32)
+    b := ab.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        t := x[i*dx+px]
+        m := ceil(log2(b[i*db+pb]))
+        q := round(min(max(log2(abs(t)), m - r), m))
+        y[i*dy+py] = sign(t) * pow(2.0, q)
+    }
+}
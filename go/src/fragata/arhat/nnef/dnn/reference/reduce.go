@@ -41,6 +41,28 @@ func Reduce(op api.ReduceOp, input *Tensor, output *Tensor) error {
     return nil
 }
 
+// Computes mean and variance in a single pass over input using
+// Welford updates; variance holds sums of squared deviations until
+// they are scaled at the end.
+
+func Moments(input *Tensor, mean *Tensor, variance *Tensor) error {
+    assert(mean.volume == variance.volume)
+    fillFloat(mean.FloatData(), 0.0)
+    fillFloat(variance.FloatData(), 0.0)
+    var xv, yv TensorView
+    rank := mean.rank
+    xv.Init(input, rank)
+    yv.Init(mean, rank)
+    state := &momentsState{
+        mean: mean.FloatData(),
+        m2: variance.FloatData(),
+        count: make([]int, mean.volume),
+    }
+    momentsLoop(0, &xv, &yv, state)
+    scaleFloat(state.m2, float32(mean.volume) / float32(input.volume))
+    return nil
+}
+
 // implementation
 
 func reduceInit(op api.ReduceOp, output *Tensor) {
@@ -108,6 +130,40 @@ func reduceLoop(level int, x *TensorView, y *TensorView, kernel reduceKernel) {
     }
 }
 
+// Mean and variance have identical shapes, hence share view offsets
+
+type momentsState struct {
+    mean []float32
+    m2 []float32
+    count []int
+}
+
+func momentsLoop(level int, x *TensorView, y *TensorView, state *momentsState) {
+    xVolume := x.volume[level]
+    yVolume := y.volume[level]
+    if yVolume == xVolume || yVolume == 1 {
+        dy := btoi(yVolume == xVolume)
+        momentsWelfordFloat(
+            xVolume,
+            x.data.([]float32),
+            x.offset[level],
+            1,
+            state,
+            y.offset[level],
+            dy)
+    } else {
+        assert(level + 1 < y.rank)
+        x.Start(level)
+        y.Start(level)
+        n := x.shape[level]
+        for i := 0; i < n; i++ {
+            momentsLoop(level+1, x, y, state) 
+            x.Next(level)
+            y.Next(level)
+        }
+    }
+}
+
 // kernels
 
 func reduceSumFloat(
@@ -185,3 +241,23 @@ func reduceAllBool(
     }
 }
 
+func momentsWelfordFloat(
+        n int,
+        x []float32,
+        px int,
+        dx int,
+        state *momentsState,
+        py int,
+        dy int) {
+    mean := state.mean
+    m2 := state.m2
+    count := state.count
+    for i := 0; i < n; i++ {
+        k := i * dy + py
+        v := x[i*dx+px]
+        count[k]++
+        delta := v - mean[k]
+        mean[k] += delta / float32(count[k])
+        m2[k] += delta * (v - mean[k])
+    }
+}
@@ -0,0 +1,298 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import (
+    "fmt"
+    "math"
+    "fragata/arhat/nnef/dnn/api"
+)
+
+// interface
+
+// ROI coordinates are given per row as (y1, x1, y2, x2) in input pixel
+// units; regions span [y1, y2) x [x1, x2).
+
+func RoiPool(
+        op api.PoolOp,
+        input *Tensor,
+        rois *Tensor,
+        batchIndex *Tensor,
+        output *Tensor) error {
+    if err := checkRoiInput(input); err != nil {
+        return err
+    }
+    assert(op == api.OpAvgPool || op == api.OpMaxPool)
+    inputData := input.FloatData()
+    roisData := rois.FloatData()
+    indexData := batchIndex.IntData()
+    outputData := output.FloatData()
+    channels := input.shape[1]
+    inputHeight := input.shape[2]
+    inputWidth := input.shape[3]
+    outputHeight := output.shape[2]
+    outputWidth := output.shape[3]
+    inputSize := inputHeight * inputWidth
+    outputSize := outputHeight * outputWidth
+    count := output.shape[0]
+    for n := 0; n < count; n++ {
+        roi := roisData[4*n:4*n+4]
+        startY := int(round(roi[0]))
+        startX := int(round(roi[1]))
+        height := int(round(roi[2])) - startY
+        if height < 1 {
+            height = 1
+        }
+        width := int(round(roi[3])) - startX
+        if width < 1 {
+            width = 1
+        }
+        binHeight := float64(height) / float64(outputHeight)
+        binWidth := float64(width) / float64(outputWidth)
+        b := indexData[n]
+        for c := 0; c < channels; c++ {
+            x := inputData[(b*channels+c)*inputSize:]
+            y := outputData[(n*channels+c)*outputSize:]
+            for i := 0; i < outputHeight; i++ {
+                y0 := startY + int(math.Floor(float64(i) * binHeight))
+                y1 := startY + int(math.Ceil(float64(i+1) * binHeight))
+                y0 = clipInt(y0, 0, inputHeight)
+                y1 = clipInt(y1, 0, inputHeight)
+                for j := 0; j < outputWidth; j++ {
+                    x0 := startX + int(math.Floor(float64(j) * binWidth))
+                    x1 := startX + int(math.Ceil(float64(j+1) * binWidth))
+                    x0 = clipInt(x0, 0, inputWidth)
+                    x1 = clipInt(x1, 0, inputWidth)
+                    y[i*outputWidth+j] = roiPoolBinFloat(op, x, inputWidth, y0, y1, x0, x1)
+                }
+            }
+        }
+    }
+    return nil
+}
+
+func RoiResample(
+        method api.UpsampleMethod,
+        input *Tensor,
+        rois *Tensor,
+        batchIndex *Tensor,
+        output *Tensor) error {
+    if err := checkRoiInput(input); err != nil {
+        return err
+    }
+    inputData := input.FloatData()
+    roisData := rois.FloatData()
+    indexData := batchIndex.IntData()
+    outputData := output.FloatData()
+    channels := input.shape[1]
+    inputHeight := input.shape[2]
+    inputWidth := input.shape[3]
+    outputHeight := output.shape[2]
+    outputWidth := output.shape[3]
+    inputSize := inputHeight * inputWidth
+    outputSize := outputHeight * outputWidth
+    count := output.shape[0]
+    coordY := make([]float64, outputHeight)
+    coordX := make([]float64, outputWidth)
+    for n := 0; n < count; n++ {
+        roi := roisData[4*n:4*n+4]
+        for i := 0; i < outputHeight; i++ {
+            coordY[i] = roiCoord(method, i, outputHeight, float64(roi[0]), float64(roi[2]))
+        }
+        for j := 0; j < outputWidth; j++ {
+            coordX[j] = roiCoord(method, j, outputWidth, float64(roi[1]), float64(roi[3]))
+        }
+        b := indexData[n]
+        for c := 0; c < channels; c++ {
+            x := inputData[(b*channels+c)*inputSize:]
+            y := outputData[(n*channels+c)*outputSize:]
+            for i := 0; i < outputHeight; i++ {
+                for j := 0; j < outputWidth; j++ {
+                    y[i*outputWidth+j] = 
+                        bilinearFloat(x, inputHeight, inputWidth, coordY[i], coordX[j])
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// ROI align samples each output bin at samplingRate points per axis
+// as ROI resample does and pools the samples.
+
+func RoiAlign(
+        op api.PoolOp,
+        method api.UpsampleMethod,
+        input *Tensor,
+        rois *Tensor,
+        batchIndex *Tensor,
+        output *Tensor,
+        samplingRate []int) error {
+    if err := checkRoiInput(input); err != nil {
+        return err
+    }
+    assert(op == api.OpAvgPool || op == api.OpMaxPool)
+    inputData := input.FloatData()
+    roisData := rois.FloatData()
+    indexData := batchIndex.IntData()
+    outputData := output.FloatData()
+    channels := input.shape[1]
+    inputHeight := input.shape[2]
+    inputWidth := input.shape[3]
+    outputHeight := output.shape[2]
+    outputWidth := output.shape[3]
+    inputSize := inputHeight * inputWidth
+    outputSize := outputHeight * outputWidth
+    rateY := samplingRate[0]
+    rateX := samplingRate[1]
+    count := output.shape[0]
+    coordY := make([]float64, outputHeight*rateY)
+    coordX := make([]float64, outputWidth*rateX)
+    for n := 0; n < count; n++ {
+        roi := roisData[4*n:4*n+4]
+        for i := range coordY {
+            coordY[i] = roiCoord(method, i, len(coordY), float64(roi[0]), float64(roi[2]))
+        }
+        for j := range coordX {
+            coordX[j] = roiCoord(method, j, len(coordX), float64(roi[1]), float64(roi[3]))
+        }
+        b := indexData[n]
+        for c := 0; c < channels; c++ {
+            x := inputData[(b*channels+c)*inputSize:]
+            y := outputData[(n*channels+c)*outputSize:]
+            for i := 0; i < outputHeight; i++ {
+                for j := 0; j < outputWidth; j++ {
+                    y[i*outputWidth+j] = 
+                        roiAlignBinFloat(
+                            op,
+                            x, 
+                            inputHeight, 
+                            inputWidth, 
+                            coordY[i*rateY:(i+1)*rateY], 
+                            coordX[j*rateX:(j+1)*rateX])
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// implementation
+
+func checkRoiInput(input *Tensor) error {
+    if input.rank != 4 {
+        return fmt.Errorf("ROI operations require input of rank 4, got rank %d", input.rank)
+    }
+    return nil
+}
+
+func roiPoolBinFloat(
+        op api.PoolOp, 
+        data []float32, 
+        width int, 
+        y0 int, 
+        y1 int, 
+        x0 int, 
+        x1 int) float32 {
+    if y1 <= y0 || x1 <= x0 {
+        return float32(0.0)
+    }
+    if op == api.OpMaxPool {
+        result := negInf
+        for i := y0; i < y1; i++ {
+            for j := x0; j < x1; j++ {
+                result = max(result, data[i*width+j])
+            }
+        }
+        return result
+    }
+    sum := float32(0.0)
+    for i := y0; i < y1; i++ {
+        for j := x0; j < x1; j++ {
+            sum += data[i*width+j]
+        }
+    }
+    return sum / float32((y1 - y0) * (x1 - x0))
+}
+
+func roiAlignBinFloat(
+        op api.PoolOp,
+        data []float32,
+        height int,
+        width int,
+        coordY []float64,
+        coordX []float64) float32 {
+    if op == api.OpMaxPool {
+        result := negInf
+        for _, y := range coordY {
+            for _, x := range coordX {
+                result = max(result, bilinearFloat(data, height, width, y, x))
+            }
+        }
+        return result
+    }
+    sum := float32(0.0)
+    for _, y := range coordY {
+        for _, x := range coordX {
+            sum += bilinearFloat(data, height, width, y, x)
+        }
+    }
+    return sum / float32(len(coordY) * len(coordX))
+}
+
+func roiCoord(method api.UpsampleMethod, index int, count int, start float64, end float64) float64 {
+    size := end - start
+    switch method {
+    case api.UpsampleSymmetric:
+        return start + (float64(index) + 0.5) * size / float64(count) - 0.5
+    case api.UpsampleAsymmetric:
+        return start + float64(index) * size / float64(count)
+    case api.UpsampleAligned:
+        if count == 1 {
+            return start + 0.5 * (size - 1.0)
+        }
+        return start + float64(index) * (size - 1.0) / float64(count - 1)
+    default:
+        assert(false)
+        return 0.0
+    }
+}
+
+// samples outside the input are clamped to the border
+func bilinearFloat(data []float32, height int, width int, y float64, x float64) float32 {
+    y = math.Max(0.0, math.Min(y, float64(height - 1)))
+    x = math.Max(0.0, math.Min(x, float64(width - 1)))
+    y0 := int(y)
+    x0 := int(x)
+    y1 := y0 + 1
+    if y1 >= height {
+        y1 = height - 1
+    }
+    x1 := x0 + 1
+    if x1 >= width {
+        x1 = width - 1
+    }
+    wy := float32(y - float64(y0))
+    wx := float32(x - float64(x0))
+    v00 := data[y0*width+x0]
+    v01 := data[y0*width+x1]
+    v10 := data[y1*width+x0]
+    v11 := data[y1*width+x1]
+    v0 := v00 + (v01 - v00) * wx
+    v1 := v10 + (v11 - v10) * wx
+    return v0 + (v1 - v0) * wy
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import "fragata/arhat/nnef/dnn/api"
+
+// interface
+
+func Sample(
+        transposed bool,
+        input *Tensor,
+        index *Tensor,
+        output *Tensor,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) error {
+    if transposed {
+        fillFloat(input.FloatData(), 0.0)
+    }
+    kernel := getSampleKernelFloat(transposed)
+    kernel(
+        input.FloatData(),
+        index.IntData(),
+        output.FloatData(),
+        input.shape,
+        output.shape,
+        size,
+        padding,
+        stride,
+        dilation,
+        border)
+    return nil
+}
+
+// implementation
+
+// Window geometry is shared with pooling: the index tensor has the shape
+// of the pooled tensor and holds flattened positions within each window.
+
+type sampleKernelFloat func(
+        inputData []float32,
+        indexData []int,
+        outputData []float32,
+        inputShape []int,
+        outputShape []int,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border)
+
+func getSampleKernelFloat(transposed bool) sampleKernelFloat {
+    if transposed {
+        return sampleCoreTFloat
+    } else {
+        return sampleCoreNFloat
+    }
+}
+
+// Decodes flattened window position into kernel index.
+
+func sampleKernelIndex(kernelIndex []int, offset int, size []int) {
+    for i := len(size) - 1; i >= 0; i-- {
+        kernelIndex[i] = offset % size[i]
+        offset /= size[i]
+    }
+}
+
+// kernels (normal)
+
+func sampleCoreNFloat(
+        inputData []float32,
+        indexData []int,
+        outputData []float32,
+        inputShape []int,
+        outputShape []int,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) {
+    rank := len(inputShape)
+    var inputIndex, kernelIndex [ndMaxRank]int
+    var outputLoop NdLoop
+    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        outputIndex := outputLoop.Index()
+        outputOffset := NdOffset(outputShape, outputIndex)
+        sampleKernelIndex(kernelIndex[:rank], indexData[outputOffset], size)
+        poolWindowIndex(
+            inputIndex[:rank], 
+            outputIndex, 
+            kernelIndex[:rank], 
+            padding, 
+            stride, 
+            dilation)
+        if borderIndexN(border, inputIndex[:rank], inputShape) {
+            inputOffset := NdOffset(inputShape, inputIndex[:rank])
+            outputData[outputOffset] = inputData[inputOffset]
+        } else {
+            outputData[outputOffset] = float32(0.0)
+        }
+    }
+}
+
+// kernels (transposed)
+
+func sampleCoreTFloat(
+        inputData []float32,
+        indexData []int,
+        outputData []float32,
+        inputShape []int,
+        outputShape []int,
+        size []int,
+        padding []int,
+        stride []int,
+        dilation []int,
+        border api.Border) {
+    rank := len(inputShape)
+    var inputIndex, kernelIndex [ndMaxRank]int
+    var outputLoop NdLoop
+    for outputLoop.Start(outputShape); outputLoop.Test(); outputLoop.Next() {
+        outputIndex := outputLoop.Index()
+        outputOffset := NdOffset(outputShape, outputIndex)
+        sampleKernelIndex(kernelIndex[:rank], indexData[outputOffset], size)
+        poolWindowIndex(
+            inputIndex[:rank], 
+            outputIndex, 
+            kernelIndex[:rank], 
+            padding, 
+            stride, 
+            dilation)
+        if borderIndexN(border, inputIndex[:rank], inputShape) {
+            inputOffset := NdOffset(inputShape, inputIndex[:rank])
+            inputData[inputOffset] += outputData[outputOffset]
+        }
+    }
+}
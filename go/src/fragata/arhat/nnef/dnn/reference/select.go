@@ -129,7 +129,7 @@ func selectBool(
     z := az.([]bool)
     for i := 0; i < n; i++ {
         if c[i*dc+pc] {
-            z[i*dz+pz] = x[i*dx+pz]
+            z[i*dz+pz] = x[i*dx+px]
         } else {
             z[i*dz+pz] = y[i*dy+py]
         }
@@ -156,7 +156,7 @@ func selectInt(
     z := az.([]int)
     for i := 0; i < n; i++ {
         if c[i*dc+pc] {
-            z[i*dz+pz] = x[i*dx+pz]
+            z[i*dz+pz] = x[i*dx+px]
         } else {
             z[i*dz+pz] = y[i*dy+py]
         }
@@ -183,7 +183,7 @@ func selectFloat(
     z := az.([]float32)
     for i := 0; i < n; i++ {
         if c[i*dc+pc] {
-            z[i*dz+pz] = x[i*dx+pz]
+            z[i*dz+pz] = x[i*dx+px]
         } else {
             z[i*dz+pz] = y[i*dy+py]
         }
@@ -27,7 +27,7 @@ import "fragata/arhat/nnef/dnn/api"
 // interface
 
 func Slice(input *Tensor, output *Tensor, offset []int) error {
-    kernel := getSliceKernel(output.dtype, output.rank)
+    kernel := getSliceKernel(output.dtype)
     kernel(input, output, offset)
     return nil
 }
@@ -36,293 +36,71 @@ func Slice(input *Tensor, output *Tensor, offset []int) error {
 
 type sliceKernel func(input *Tensor, output *Tensor, offset []int)
 
-var sliceKernels = [...][5]sliceKernel{
-    api.DtypeBool: [5]sliceKernel{
-        slice1Bool,
-        slice2Bool,
-        slice3Bool,
-        slice4Bool,
-        slice5Bool,
-    },
-    api.DtypeInt: [5]sliceKernel{
-        slice1Int,
-        slice2Int,
-        slice3Int,
-        slice4Int,
-        slice5Int,
-    },
-    api.DtypeFloat: [5]sliceKernel{
-        slice1Float,
-        slice2Float,
-        slice3Float,
-        slice4Float,
-        slice5Float,
-    },
+var sliceKernels = [...]sliceKernel{
+    api.DtypeBool: sliceBool,
+    api.DtypeInt: sliceInt,
+    api.DtypeFloat: sliceFloat,
 }
 
-func getSliceKernel(dtype api.Dtype, rank int) sliceKernel {
-    assert(rank >= 1 && rank <= 5)
-    return sliceKernels[dtype][rank-1]
+func getSliceKernel(dtype api.Dtype) sliceKernel {
+    return sliceKernels[dtype]
 }
 
-// kernels (bool)
+// kernels
 
-func slice1Bool(input *Tensor, output *Tensor, offset []int) {
+func sliceBool(input *Tensor, output *Tensor, offset []int) {
     inputData := input.BoolData()
     outputData := output.BoolData()
-    shape0 := output.shape[0]
-    offset0 := offset[0]
-    for outputIndex := 0; outputIndex < shape0; outputIndex++ {
-        inputIndex := outputIndex + offset0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func slice2Bool(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice3Bool(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice4Bool(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice5Bool(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputIndex[4] = outputIndex[4] + offset[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-// kernels (int)
-
-func slice1Int(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    shape0 := output.shape[0]
-    offset0 := offset[0]
-    for outputIndex := 0; outputIndex < shape0; outputIndex++ {
-        inputIndex := outputIndex + offset0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func slice2Int(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice3Int(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.IntData()
-    outputData := output.IntData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] + offset[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
 
-func slice4Int(input *Tensor, output *Tensor, offset []int) {
+func sliceInt(input *Tensor, output *Tensor, offset []int) {
     inputData := input.IntData()
     outputData := output.IntData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice5Int(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputIndex[4] = outputIndex[4] + offset[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-// kernels (float)
-
-func slice1Float(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    shape0 := output.shape[0]
-    offset0 := offset[0]
-    for outputIndex := 0; outputIndex < shape0; outputIndex++ {
-        inputIndex := outputIndex + offset0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func slice2Float(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] + offset[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
 
-func slice3Float(input *Tensor, output *Tensor, offset []int) {
+func sliceFloat(input *Tensor, output *Tensor, offset []int) {
     inputData := input.FloatData()
     outputData := output.FloatData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] + offset[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
-
-func slice4Float(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func slice5Float(input *Tensor, output *Tensor, offset []int) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] + offset[0]
-        inputIndex[1] = outputIndex[1] + offset[1]
-        inputIndex[2] = outputIndex[2] + offset[2]
-        inputIndex[3] = outputIndex[3] + offset[3]
-        inputIndex[4] = outputIndex[4] + offset[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
@@ -24,42 +24,40 @@ package reference
 
 // interface
 
-func Softmax(input *Tensor, output *Tensor, axis int) error {
-    softmaxLoopFloat(input, output, axis)
+func Softmax(input *Tensor, output *Tensor, axes []int) error {
+    softmaxLoopFloat(input, output, axes)
     return nil
 }
 
 // implementation
 
-func softmaxLoopFloat(input *Tensor, output *Tensor, axis int) {
+func softmaxLoopFloat(input *Tensor, output *Tensor, axes []int) {
     inputData := input.FloatData()
     outputData := output.FloatData()
     inputShape := input.shape
-    batch := volumeOf(inputShape[:axis])
-    channels := inputShape[axis]
-    size := volumeOf(inputShape[axis+1:])
-    volume := channels * size
-    for i := 0; i < batch; i++ {
-        for j := 0; j < size; j++ {
-            offset := volume * i + j
-            softmaxFloat(channels, size, inputData[offset:], outputData[offset:])
-        }
+    outerShape, offsets := reducedLayout(inputShape, axes)
+    var loop NdLoop
+    for loop.Start(outerShape); loop.Test(); loop.Next() {
+        base := NdOffset(inputShape, loop.Index())
+        softmaxFloat(offsets, inputData[base:], outputData[base:])
     }
 }
 
-func softmaxFloat(n int, m int, x []float32, y []float32) {
-    xmax := x[0]
+func softmaxFloat(offsets []int, x []float32, y []float32) {
+    n := len(offsets)
+    xmax := x[offsets[0]]
     for i := 1; i < n; i++ {
-        xmax = max(xmax, x[i*m])
+        xmax = max(xmax, x[offsets[i]])
     }
     ysum := float32(0)
     for i := 0; i < n; i++ {
-        yval := exp(x[i*m]-xmax)
-        y[i*m] = yval
+        k := offsets[i]
+        yval := exp(x[k]-xmax)
+        y[k] = yval
         ysum += yval
     }
     for i := 0; i < n; i++ {
-        y[i*m] /= ysum
+        y[offsets[i]] /= ysum
     }
 }
 
@@ -46,7 +46,7 @@ func splitLoop(singular bool, x *Tensor, y []*Tensor, axis int) {
     for i := 0; i < b; i++ {
         for j := 0; j < n; j++ {
             if !singular {
-                size = y[j].shape[axis]
+                size = y[j].shape[axis] * m
             }
             kernel(size, x.data, px, y[j].data, i*size)
             px += size
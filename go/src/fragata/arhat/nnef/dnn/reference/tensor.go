@@ -34,6 +34,8 @@ type Tensor struct {
     volume int
     shape []int
     data interface{}
+    version int               // number of times data was set by Fill or Copy
+    winograd *winogradFilter  // transform cached when tensor is used as filter
 }
 
 func NewTensor(dtype api.Dtype, shape []int) (*Tensor, error) {
@@ -48,6 +50,21 @@ func(t *Tensor) Init(dtype api.Dtype, shape []int) {
     t.volume = volumeOf(shape)
     t.shape = cloneShape(shape)
     t.data = makeData(t.dtype, t.volume)
+    t.version = 0
+    t.winograd = nil
+}
+
+// Creates tensor sharing storage of buffer
+
+func NewTensorIn(buffer *Buffer, shape []int) (*Tensor, error) {
+    t := new(Tensor)
+    t.dtype = buffer.dtype
+    t.rank = len(shape)
+    t.volume = volumeOf(shape)
+    t.shape = cloneShape(shape)
+    assert(t.volume <= buffer.volume)
+    t.data = sliceData(buffer.data, t.volume)
+    return t, nil
 }
 
 func(t *Tensor) Dtype() api.Dtype {
@@ -81,6 +98,32 @@ func(t *Tensor) FloatData() []float32 {
     return t.data.([]float32)
 }
 
+//
+//    Buffer
+//
+
+type Buffer struct {
+    dtype api.Dtype
+    volume int
+    data interface{}
+}
+
+func NewBuffer(dtype api.Dtype, volume int) (*Buffer, error) {
+    b := new(Buffer)
+    b.dtype = dtype
+    b.volume = volume
+    b.data = makeData(dtype, volume)
+    return b, nil
+}
+
+func(b *Buffer) Dtype() api.Dtype {
+    return b.dtype
+}
+
+func(b *Buffer) Volume() int {
+    return b.volume
+}
+
 //
 //    TensorView
 //
@@ -27,7 +27,7 @@ import "fragata/arhat/nnef/dnn/api"
 // interface
 
 func Tile(input *Tensor, output *Tensor) error {
-    kernel := getTileKernel(output.dtype, output.rank)
+    kernel := getTileKernel(output.dtype)
     kernel(input, output)
     return nil
 }
@@ -36,293 +36,71 @@ func Tile(input *Tensor, output *Tensor) error {
 
 type tileKernel func(input *Tensor, output *Tensor)
 
-var tileKernels = [...][5]tileKernel{
-    api.DtypeBool: [5]tileKernel{
-        tile1Bool,
-        tile2Bool,
-        tile3Bool,
-        tile4Bool,
-        tile5Bool,
-    },
-    api.DtypeInt: [5]tileKernel{
-        tile1Int,
-        tile2Int,
-        tile3Int,
-        tile4Int,
-        tile5Int,
-    },
-    api.DtypeFloat: [5]tileKernel{
-        tile1Float,
-        tile2Float,
-        tile3Float,
-        tile4Float,
-        tile5Float,
-    },
+var tileKernels = [...]tileKernel{
+    api.DtypeBool: tileBool,
+    api.DtypeInt: tileInt,
+    api.DtypeFloat: tileFloat,
 }
 
-func getTileKernel(dtype api.Dtype, rank int) tileKernel {
-    assert(rank >= 1 && rank <= 5)
-    return tileKernels[dtype][rank-1]
+func getTileKernel(dtype api.Dtype) tileKernel {
+    return tileKernels[dtype]
 }
 
-// kernels (bool)
+// kernels
 
-func tile1Bool(input *Tensor, output *Tensor) {
+func tileBool(input *Tensor, output *Tensor) {
     inputData := input.BoolData()
     outputData := output.BoolData()
-    inputShape0 := input.shape[0]
-    outputShape0 := output.shape[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        inputIndex := outputIndex % inputShape0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func tile2Bool(input *Tensor, output *Tensor) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile3Bool(input *Tensor, output *Tensor) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile4Bool(input *Tensor, output *Tensor) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile5Bool(input *Tensor, output *Tensor) {
-    inputData := input.BoolData()
-    outputData := output.BoolData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputIndex[4] = outputIndex[4] % inputShape[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-// kernels (int)
-
-func tile1Int(input *Tensor, output *Tensor) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    inputShape0 := input.shape[0]
-    outputShape0 := output.shape[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        inputIndex := outputIndex % inputShape0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func tile2Int(input *Tensor, output *Tensor) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile3Int(input *Tensor, output *Tensor) {
-    inputData := input.IntData()
-    outputData := output.IntData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] % inputShape[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
 
-func tile4Int(input *Tensor, output *Tensor) {
+func tileInt(input *Tensor, output *Tensor) {
     inputData := input.IntData()
     outputData := output.IntData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile5Int(input *Tensor, output *Tensor) {
-    inputData := input.IntData()
-    outputData := output.IntData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputIndex[4] = outputIndex[4] % inputShape[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-// kernels (float)
-
-func tile1Float(input *Tensor, output *Tensor) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape0 := input.shape[0]
-    outputShape0 := output.shape[0]
-    for outputIndex := 0; outputIndex < outputShape0; outputIndex++ {
-        inputIndex := outputIndex % inputShape0
-        outputData[outputIndex] = inputData[inputIndex]
-    }
-}
-
-func tile2Float(input *Tensor, output *Tensor) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [2]int
-    var loop NdLoop2
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputOffset := NdOffset2(inputShape, inputIndex[:])
-        outputOffset := NdOffset2(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] % inputShape[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
 
-func tile3Float(input *Tensor, output *Tensor) {
+func tileFloat(input *Tensor, output *Tensor) {
     inputData := input.FloatData()
     outputData := output.FloatData()
     inputShape := input.shape
     outputShape := output.shape
-    var inputIndex [3]int
-    var loop NdLoop3
+    rank := len(outputShape)
+    var inputIndex [ndMaxRank]int
+    var loop NdLoop
     for loop.Start(outputShape); loop.Test(); loop.Next() {
         outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputOffset := NdOffset3(inputShape, inputIndex[:])
-        outputOffset := NdOffset3(outputShape, outputIndex)
+        for i := 0; i < rank; i++ {
+            inputIndex[i] = outputIndex[i] % inputShape[i]
+        }
+        inputOffset := NdOffset(inputShape, inputIndex[:rank])
+        outputOffset := NdOffset(outputShape, outputIndex)
         outputData[outputOffset] = inputData[inputOffset]
     }
 }
-
-func tile4Float(input *Tensor, output *Tensor) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [4]int
-    var loop NdLoop4
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputOffset := NdOffset4(inputShape, inputIndex[:])
-        outputOffset := NdOffset4(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
-func tile5Float(input *Tensor, output *Tensor) {
-    inputData := input.FloatData()
-    outputData := output.FloatData()
-    inputShape := input.shape
-    outputShape := output.shape
-    var inputIndex [5]int
-    var loop NdLoop5
-    for loop.Start(outputShape); loop.Test(); loop.Next() {
-        outputIndex := loop.Index()
-        inputIndex[0] = outputIndex[0] % inputShape[0]
-        inputIndex[1] = outputIndex[1] % inputShape[1]
-        inputIndex[2] = outputIndex[2] % inputShape[2]
-        inputIndex[3] = outputIndex[3] % inputShape[3]
-        inputIndex[4] = outputIndex[4] % inputShape[4]
-        inputOffset := NdOffset5(inputShape, inputIndex[:])
-        outputOffset := NdOffset5(outputShape, outputIndex)
-        outputData[outputOffset] = inputData[inputOffset]
-    }
-}
-
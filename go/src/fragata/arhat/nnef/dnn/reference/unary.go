@@ -27,43 +27,81 @@ import "fragata/arhat/nnef/dnn/api"
 // interface
 
 func Unary(op api.UnaryOp, x *Tensor, y *Tensor) error {
-    kernel := getUnaryKernel(op)
+    kernel := getUnaryKernel(op, x.dtype)
+    if kernel == nil {
+        return api.ErrNotSupported
+    }
     kernel(x.volume, x.data, y.data)
     return nil
 }
 
+func UnaryParam(op api.UnaryParamOp, x *Tensor, y *Tensor, alpha float32) error {
+    kernel := getUnaryParamKernel(op)
+    kernel(x.volume, x.data, y.data, alpha)
+    return nil
+}
+
 // implementation
 
 type unaryKernel func(n int, ax interface{}, ay interface{})
 
-var unaryKernels = [...]unaryKernel{
-    api.OpNeg: unaryNegFloat,
-    api.OpNot: unaryNotBool,
-    api.OpAbs: unaryAbsFloat,
-    api.OpSign: unarySignFloat,
-    api.OpExp: unaryExpFloat,
-    api.OpLog: unaryLogFloat,
-    api.OpLog2: unaryLog2Float,
-    api.OpSin: unarySinFloat,
-    api.OpCos: unaryCosFloat,
-    api.OpRound: unaryRoundFloat,
-    api.OpFloor: unaryFloorFloat,
-    api.OpCeil: unaryCeilFloat,
-    api.OpSqrt: unarySqrtFloat,
-    api.OpSqr: unarySqrFloat,
-    api.OpRsqrt: unaryRsqrtFloat,
-    api.OpRsqr: unaryRsqrFloat,
-    api.OpRcp: unaryRcpFloat,
-    api.OpCopy: unaryCopyFloat,
-    api.OpSigmoid: unarySigmoidFloat,
-    api.OpTanh: unaryTanhFloat,
-    api.OpRelu: unaryReluFloat,
-    api.OpElu: unaryEluFloat,
-    api.OpSoftplus: unarySoftplusFloat,
-}
-
-func getUnaryKernel(op api.UnaryOp) unaryKernel {
-    return unaryKernels[op]
+var unaryKernels = [...][dtypeCount]unaryKernel{
+    api.OpNeg: {
+        api.DtypeInt: unaryNegInt,
+        api.DtypeFloat: unaryNegFloat,
+    },
+    api.OpNot: {api.DtypeBool: unaryNotBool},
+    api.OpAbs: {
+        api.DtypeInt: unaryAbsInt,
+        api.DtypeFloat: unaryAbsFloat,
+    },
+    api.OpSign: {
+        api.DtypeInt: unarySignInt,
+        api.DtypeFloat: unarySignFloat,
+    },
+    api.OpExp: {api.DtypeFloat: unaryExpFloat},
+    api.OpLog: {api.DtypeFloat: unaryLogFloat},
+    api.OpLog2: {api.DtypeFloat: unaryLog2Float},
+    api.OpSin: {api.DtypeFloat: unarySinFloat},
+    api.OpCos: {api.DtypeFloat: unaryCosFloat},
+    api.OpRound: {api.DtypeFloat: unaryRoundFloat},
+    api.OpFloor: {api.DtypeFloat: unaryFloorFloat},
+    api.OpCeil: {api.DtypeFloat: unaryCeilFloat},
+    api.OpSqrt: {api.DtypeFloat: unarySqrtFloat},
+    api.OpSqr: {
+        api.DtypeInt: unarySqrInt,
+        api.DtypeFloat: unarySqrFloat,
+    },
+    api.OpRsqrt: {api.DtypeFloat: unaryRsqrtFloat},
+    api.OpRsqr: {api.DtypeFloat: unaryRsqrFloat},
+    api.OpRcp: {api.DtypeFloat: unaryRcpFloat},
+    api.OpCopy: {
+        api.DtypeBool: unaryCopyBool,
+        api.DtypeInt: unaryCopyInt,
+        api.DtypeFloat: unaryCopyFloat,
+    },
+    api.OpSigmoid: {api.DtypeFloat: unarySigmoidFloat},
+    api.OpTanh: {api.DtypeFloat: unaryTanhFloat},
+    api.OpRelu: {api.DtypeFloat: unaryReluFloat},
+    api.OpElu: {api.DtypeFloat: unaryEluFloat},
+    api.OpSoftplus: {api.DtypeFloat: unarySoftplusFloat},
+}
+
+// returns nil if op has no kernel for dtype
+
+func getUnaryKernel(op api.UnaryOp, dtype api.Dtype) unaryKernel {
+    return unaryKernels[op][dtype]
+}
+
+type unaryParamKernel func(n int, ax interface{}, ay interface{}, alpha float32)
+
+var unaryParamKernels = [...]unaryParamKernel{
+    api.OpLeakyRelu: unaryLeakyReluFloat,
+    api.OpSoftabs: unarySoftabsFloat,
+}
+
+func getUnaryParamKernel(op api.UnaryParamOp) unaryParamKernel {
+    return unaryParamKernels[op]
 }
 
 // kernels
@@ -76,6 +114,14 @@ func unaryNegFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unaryNegInt(n int, ax interface{}, ay interface{}) {
+    x := ax.([]int)
+    y := ay.([]int)
+    for i := 0; i < n; i++ {
+        y[i] = -x[i]
+    }    
+}
+
 func unaryNotBool(n int, ax interface{}, ay interface{}) {
     x := ax.([]bool)
     y := ay.([]bool)
@@ -92,6 +138,14 @@ func unaryAbsFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unaryAbsInt(n int, ax interface{}, ay interface{}) {
+    x := ax.([]int)
+    y := ay.([]int)
+    for i := 0; i < n; i++ {
+        y[i] = absInt(x[i])
+    }    
+}
+
 func unarySignFloat(n int, ax interface{}, ay interface{}) {
     x := ax.([]float32)
     y := ay.([]float32)
@@ -100,6 +154,14 @@ func unarySignFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unarySignInt(n int, ax interface{}, ay interface{}) {
+    x := ax.([]int)
+    y := ay.([]int)
+    for i := 0; i < n; i++ {
+        y[i] = signInt(x[i])
+    }    
+}
+
 func unaryExpFloat(n int, ax interface{}, ay interface{}) {
     x := ax.([]float32)
     y := ay.([]float32)
@@ -181,6 +243,14 @@ func unarySqrFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unarySqrInt(n int, ax interface{}, ay interface{}) {
+    x := ax.([]int)
+    y := ay.([]int)
+    for i := 0; i < n; i++ {
+        y[i] = x[i] * x[i]
+    }    
+}
+
 func unaryRsqrtFloat(n int, ax interface{}, ay interface{}) {
     x := ax.([]float32)
     y := ay.([]float32)
@@ -214,6 +284,22 @@ func unaryCopyFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unaryCopyInt(n int, ax interface{}, ay interface{}) {
+    x := ax.([]int)
+    y := ay.([]int)
+    for i := 0; i < n; i++ {
+        y[i] = x[i]
+    }    
+}
+
+func unaryCopyBool(n int, ax interface{}, ay interface{}) {
+    x := ax.([]bool)
+    y := ay.([]bool)
+    for i := 0; i < n; i++ {
+        y[i] = x[i]
+    }    
+}
+
 func unarySigmoidFloat(n int, ax interface{}, ay interface{}) {
     x := ax.([]float32)
     y := ay.([]float32)
@@ -254,3 +340,22 @@ func unarySoftplusFloat(n int, ax interface{}, ay interface{}) {
     }    
 }
 
+func unaryLeakyReluFloat(n int, ax interface{}, ay interface{}, alpha float32) {
+    x := ax.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        t := x[i]
+        if t < 0.0 {
+            t *= alpha
+        }
+        y[i] = t
+    }    
+}
+
+func unarySoftabsFloat(n int, ax interface{}, ay interface{}, epsilon float32) {
+    x := ax.([]float32)
+    y := ay.([]float32)
+    for i := 0; i < n; i++ {
+        y[i] = sqrt(x[i] * x[i] + epsilon)
+    }    
+}
@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package reference
+
+import (
+    "math"
+    "fragata/arhat/nnef/dnn/api"
+)
+
+// interface
+
+func MultilinearUpsample(
+        method api.UpsampleMethod,
+        border api.Border,
+        input *Tensor,
+        output *Tensor,
+        factor []int) error {
+    assert(border == api.BorderConstant || border == api.BorderReplicate)
+    n := len(factor)
+    if n == 0 {
+        copy(output.FloatData(), input.FloatData())
+        return nil
+    }
+    // interpolation is separable: upsample one spatial axis per pass
+    shape := cloneShape(input.shape)
+    data := input.FloatData()
+    for i := 0; i < n; i++ {
+        axis := i + 2
+        inputSize := shape[axis]
+        shape[axis] *= factor[i]
+        var result []float32
+        if i == n - 1 {
+            result = output.FloatData()
+        } else {
+            result = make([]float32, volumeOf(shape))
+        }
+        upsampleAxisFloat(
+            method,
+            border,
+            data,
+            result,
+            volumeOf(shape[:axis]),
+            inputSize,
+            shape[axis],
+            volumeOf(shape[axis+1:]))
+        data = result
+    }
+    return nil
+}
+
+// implementation
+
+func upsampleAxisFloat(
+        method api.UpsampleMethod,
+        border api.Border,
+        inputData []float32,
+        outputData []float32,
+        batch int,
+        inputSize int,
+        outputSize int,
+        size int) {
+    index0 := make([]int, outputSize)
+    index1 := make([]int, outputSize)
+    weight0 := make([]float32, outputSize)
+    weight1 := make([]float32, outputSize)
+    for j := 0; j < outputSize; j++ {
+        x := upsampleCoord(method, j, inputSize, outputSize)
+        i := int(math.Floor(x))
+        w := float32(x - float64(i))
+        index0[j] = i
+        index1[j] = i + 1
+        weight0[j] = float32(1.0) - w
+        weight1[j] = w
+    }
+    inputVolume := inputSize * size
+    outputVolume := outputSize * size
+    for b := 0; b < batch; b++ {
+        x := inputData[b*inputVolume:(b+1)*inputVolume]
+        y := outputData[b*outputVolume:(b+1)*outputVolume]
+        for j := 0; j < outputSize; j++ {
+            p0, ok0 := borderIndex(border, index0[j], inputSize)
+            p1, ok1 := borderIndex(border, index1[j], inputSize)
+            w0 := weight0[j]
+            w1 := weight1[j]
+            for k := 0; k < size; k++ {
+                v := float32(0.0)
+                if ok0 {
+                    v += w0 * x[p0*size+k]
+                }
+                if ok1 {
+                    v += w1 * x[p1*size+k]
+                }
+                y[j*size+k] = v
+            }
+        }
+    }
+}
+
+func upsampleCoord(method api.UpsampleMethod, index int, inputSize int, outputSize int) float64 {
+    switch method {
+    case api.UpsampleSymmetric:
+        scale := float64(outputSize) / float64(inputSize)
+        return (float64(index) + 0.5) / scale - 0.5
+    case api.UpsampleAsymmetric:
+        scale := float64(outputSize) / float64(inputSize)
+        return float64(index) / scale
+    case api.UpsampleAligned:
+        if outputSize == 1 {
+            return 0.0
+        }
+        return float64(index * (inputSize - 1)) / float64(outputSize - 1)
+    default:
+        assert(false)
+        return 0.0
+    }
+}
@@ -24,6 +24,7 @@ package reference
 
 import (
     "fmt"
+    "unsafe"
     "fragata/arhat/nnef/dnn/api"
 )
 
@@ -31,6 +32,10 @@ import (
 //    Utility functions
 //
 
+// number of dtypes, used to size per-dtype kernel tables
+
+const dtypeCount = int(api.DtypeFloat) + 1
+
 func cloneShape(shape []int) []int {
     size := len(shape)
     if size == 0 {
@@ -49,6 +54,30 @@ func volumeOf(shape []int) int {
     return result
 }
 
+// Splits shape for reduction over axes: outer shape has reduced axes
+// set to 1, offsets address elements of reduced sub-volume relative
+// to its origin in row-major order of reduced axes.
+
+func reducedLayout(shape []int, axes []int) ([]int, []int) {
+    rank := len(shape)
+    outerShape := cloneShape(shape)
+    innerShape := make([]int, rank)
+    for i := 0; i < rank; i++ {
+        innerShape[i] = 1
+    }
+    for _, axis := range axes {
+        assert(axis >= 0 && axis < rank)
+        outerShape[axis] = 1
+        innerShape[axis] = shape[axis]
+    }
+    offsets := make([]int, 0, volumeOf(innerShape))
+    var loop NdLoop
+    for loop.Start(innerShape); loop.Test(); loop.Next() {
+        offsets = append(offsets, NdOffset(shape, loop.Index()))
+    }
+    return outerShape, offsets
+}
+
 func makeData(dtype api.Dtype, volume int) interface{} {
     switch dtype {
     case api.DtypeBool:
@@ -63,6 +92,34 @@ func makeData(dtype api.Dtype, volume int) interface{} {
     }
 }
 
+func itemSize(dtype api.Dtype) int {
+    switch dtype {
+    case api.DtypeBool:
+        return int(unsafe.Sizeof(false))
+    case api.DtypeInt:
+        return int(unsafe.Sizeof(int(0)))
+    case api.DtypeFloat:
+        return int(unsafe.Sizeof(float32(0.0)))
+    default:
+        assert(false)
+        return 0
+    }
+}
+
+func sliceData(data interface{}, volume int) interface{} {
+    switch v := data.(type) {
+    case []bool:
+        return v[:volume]
+    case []int:
+        return v[:volume]
+    case []float32:
+        return v[:volume]
+    default:
+        assert(false)
+        return nil
+    }
+}
+
 func fillBool(data []bool, value bool) {
     n := len(data)
     for i := 0; i < n; i++ {
@@ -101,6 +158,60 @@ func clipInt(value int, low int, high int) int {
     return value
 }
 
+// Maps index that can be outside [0, size) to the index of tensor item
+// that supplies its value according to the border mode. Returns false
+// if no such item exists (constant and ignore modes), in which case
+// caller must use the border value or skip the item.
+
+func borderIndex(border api.Border, index int, size int) (int, bool) {
+    if index >= 0 && index < size {
+        return index, true
+    }
+    switch border {
+    case api.BorderReplicate:
+        return clipInt(index, 0, size - 1), true
+    case api.BorderReflect:
+        if size == 1 {
+            return 0, true
+        }
+        period := 2 * (size - 1)
+        index %= period
+        if index < 0 {
+            index += period
+        }
+        if index >= size {
+            index = period - index
+        }
+        return index, true
+    case api.BorderReflectEven:
+        period := 2 * size
+        index %= period
+        if index < 0 {
+            index += period
+        }
+        if index >= size {
+            index = period - 1 - index
+        }
+        return index, true
+    default:
+        return 0, false
+    }
+}
+
+// Applies borderIndex to each item of index in place.
+
+func borderIndexN(border api.Border, index []int, shape []int) bool {
+    rank := len(shape)
+    for i := 0; i < rank; i++ {
+        k, ok := borderIndex(border, index[i], shape[i])
+        if !ok {
+            return false
+        }
+        index[i] = k
+    }
+    return true
+}
+
 func btoi(b bool) int {
     if b {
         return 1
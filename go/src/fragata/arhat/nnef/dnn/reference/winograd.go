@@ -0,0 +1,355 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+import "fragata/arhat/nnef/dnn/api"
+
+//
+//    Winograd convolution
+//
+
+// Computes 2D 3x3 convolution with stride and dilation 1 as
+// F(m x m, 3 x 3) for m = 2 or 4. Output tile is Y = At [U * V] A where
+// U = G g Gt is transformed filter and V = Bt d B is transformed input tile.
+// For each of alpha x alpha taps, products over input channels form GEMM
+// [outputChannels x inputChannels] x [inputChannels x tiles].
+
+type winogradParams struct {
+    m int          // output tile size
+    alpha int      // input tile size, m + 2
+    bt []float32   // alpha x alpha
+    g []float32    // alpha x 3
+    at []float32   // m x alpha
+}
+
+var winogradF2 = &winogradParams{
+    m: 2,
+    alpha: 4,
+    bt: []float32{
+        1, 0, -1, 0,
+        0, 1, 1, 0,
+        0, -1, 1, 0,
+        0, 1, 0, -1,
+    },
+    g: []float32{
+        1, 0, 0,
+        0.5, 0.5, 0.5,
+        0.5, -0.5, 0.5,
+        0, 0, 1,
+    },
+    at: []float32{
+        1, 1, 1, 0,
+        0, 1, -1, -1,
+    },
+}
+
+var winogradF4 = &winogradParams{
+    m: 4,
+    alpha: 6,
+    bt: []float32{
+        4, 0, -5, 0, 1, 0,
+        0, -4, -4, 1, 1, 0,
+        0, 4, -4, -1, 1, 0,
+        0, -2, -1, 2, 1, 0,
+        0, 2, -1, -2, 1, 0,
+        0, 4, 0, -5, 0, 1,
+    },
+    g: []float32{
+        1.0 / 4.0, 0, 0,
+        -1.0 / 6.0, -1.0 / 6.0, -1.0 / 6.0,
+        -1.0 / 6.0, 1.0 / 6.0, -1.0 / 6.0,
+        1.0 / 24.0, 1.0 / 12.0, 1.0 / 6.0,
+        1.0 / 24.0, -1.0 / 12.0, 1.0 / 6.0,
+        0, 0, 1,
+    },
+    at: []float32{
+        1, 1, 1, 1, 1, 0,
+        0, 1, -1, 2, -2, 0,
+        0, 1, 1, 4, 4, 0,
+        0, 1, -1, 8, -8, 1,
+    },
+}
+
+// Transformed filter is cached in filter tensor together with version
+// of data it was computed from. Filters set by Fill or Copy (typically
+// variables) are transformed once per version; filters written by other
+// kernels have version 0 and are transformed on each call.
+
+type winogradFilter struct {
+    params *winogradParams
+    version int
+    data []float32     // [alpha * alpha][outputChannels][inputChannels]
+}
+
+// implementation
+
+const (
+    winogradMinChannels = 8
+    winogradTileBlock = 64
+    winogradMinTiles = 4
+)
+
+func useWinograd(
+        transposed bool,
+        input *Tensor,
+        filter *Tensor,
+        output *Tensor,
+        stride []int,
+        dilation []int) bool {
+    if transposed || input.rank != 4 {
+        return false
+    }
+    if filter.shape[2] != 3 || filter.shape[3] != 3 {
+        return false
+    }
+    for i := 0; i < 2; i++ {
+        if stride[i] != 1 || dilation[i] != 1 {
+            return false
+        }
+    }
+    return (input.shape[1] >= winogradMinChannels && output.shape[1] >= winogradMinChannels)
+}
+
+// Larger tiles save more multiplications but are less accurate
+// and waste more work on partial tiles of small outputs
+
+func getWinogradParams(output *Tensor) *winogradParams {
+    if output.shape[2] >= 16 && output.shape[3] >= 16 {
+        return winogradF4
+    }
+    return winogradF2
+}
+
+func getWinogradFilter(filter *Tensor, params *winogradParams) []float32 {
+    cache := filter.winograd
+    if cache != nil && cache.params == params && cache.version == filter.version {
+        return cache.data
+    }
+    data := winogradFilterFloat(params, filter.FloatData(), filter.shape[0], filter.shape[1])
+    if filter.version != 0 {
+        filter.winograd = &winogradFilter{params: params, version: filter.version, data: data}
+    }
+    return data
+}
+
+// Tasks are blocks of tiles of all batch items; each output item
+// is computed from single tile, so results do not depend on workers.
+
+func winogradConvLoopFloat(
+        workers int,
+        input *Tensor,
+        filter *Tensor,
+        bias *Tensor,
+        output *Tensor,
+        padding []int,
+        border api.Border) {
+    convBiasFloat(bias.FloatData(), output.FloatData(), bias.shape, output.shape)
+    params := getWinogradParams(output)
+    filterData := getWinogradFilter(filter, params)
+    inputData := input.FloatData()
+    outputData := output.FloatData()
+    batch := output.shape[0]
+    inputChannels := input.shape[1]
+    outputChannels := output.shape[1]
+    taps := params.alpha * params.alpha
+    tilesW := (output.shape[3] + params.m - 1) / params.m
+    tiles := ((output.shape[2] + params.m - 1) / params.m) * tilesW
+    width := convTileWidth(workers, batch, tiles, winogradTileBlock, winogradMinTiles)
+    blocks := (tiles + width - 1) / width
+    parallelFor(workers, batch * blocks, func(begin int, end int) {
+        v := make([]float32, taps*inputChannels*width)
+        y := make([]float32, taps*outputChannels*width)
+        for t := begin; t < end; t++ {
+            b := t / blocks
+            p0 := (t % blocks) * width
+            nt := minInt(width, tiles-p0)
+            winogradInputFloat(
+                params,
+                inputData[getConvOffset2(input.shape, b, 0):],
+                v,
+                input.shape[2:],
+                inputChannels,
+                padding,
+                border,
+                tilesW,
+                p0,
+                nt)
+            fillFloat(y[:taps*outputChannels*nt], float32(0.0))
+            for xi := 0; xi < taps; xi++ {
+                gemmPackedFloat(
+                    false,
+                    inputChannels,
+                    0,
+                    outputChannels,
+                    filterData[xi*outputChannels*inputChannels:],
+                    inputChannels,
+                    v[xi*inputChannels*nt:(xi+1)*inputChannels*nt],
+                    nt,
+                    y[xi*outputChannels*nt:],
+                    nt)
+            }
+            winogradOutputFloat(
+                params,
+                y,
+                outputData[getConvOffset2(output.shape, b, 0):],
+                output.shape[2:],
+                outputChannels,
+                tilesW,
+                p0,
+                nt)
+        }
+    })
+}
+
+// kernels
+
+// computes y = mat * x * transpose(mat) where mat is rows x cols and x is cols x cols
+
+func winogradProduct(mat []float32, rows int, cols int, x []float32, tmp []float32, y []float32) {
+    for i := 0; i < rows; i++ {
+        for j := 0; j < cols; j++ {
+            s := float32(0.0)
+            for l := 0; l < cols; l++ {
+                s += mat[i*cols+l] * x[l*cols+j]
+            }
+            tmp[i*cols+j] = s
+        }
+    }
+    for i := 0; i < rows; i++ {
+        for j := 0; j < rows; j++ {
+            s := float32(0.0)
+            for l := 0; l < cols; l++ {
+                s += tmp[i*cols+l] * mat[j*cols+l]
+            }
+            y[i*rows+j] = s
+        }
+    }
+}
+
+func winogradFilterFloat(
+        params *winogradParams,
+        filterData []float32,
+        outputChannels int,
+        inputChannels int) []float32 {
+    alpha := params.alpha
+    taps := alpha * alpha
+    data := make([]float32, taps*outputChannels*inputChannels)
+    tmp := make([]float32, alpha*3)
+    u := make([]float32, taps)
+    for z := 0; z < outputChannels; z++ {
+        for c := 0; c < inputChannels; c++ {
+            g := filterData[(z*inputChannels+c)*9:]
+            winogradProduct(params.g, alpha, 3, g, tmp, u)
+            for xi := 0; xi < taps; xi++ {
+                data[(xi*outputChannels+z)*inputChannels+c] = u[xi]
+            }
+        }
+    }
+    return data
+}
+
+// Transforms input tiles [p0, p0+nt) of all channels into
+// v[alpha * alpha][channels][nt]
+
+func winogradInputFloat(
+        params *winogradParams,
+        inputData []float32,
+        v []float32,
+        inputShape []int,
+        channels int,
+        padding []int,
+        border api.Border,
+        tilesW int,
+        p0 int,
+        nt int) {
+    m := params.m
+    alpha := params.alpha
+    taps := alpha * alpha
+    h := inputShape[0]
+    w := inputShape[1]
+    d := make([]float32, taps)
+    tmp := make([]float32, taps)
+    u := make([]float32, taps)
+    for p := 0; p < nt; p++ {
+        y0 := ((p0 + p) / tilesW) * m - padding[0]
+        x0 := ((p0 + p) % tilesW) * m - padding[1]
+        inner := (y0 >= 0 && x0 >= 0 && y0 + alpha <= h && x0 + alpha <= w)
+        for c := 0; c < channels; c++ {
+            x := inputData[c*h*w:]
+            if inner {
+                for i := 0; i < alpha; i++ {
+                    copy(d[i*alpha:(i+1)*alpha], x[(y0+i)*w+x0:])
+                }
+            } else {
+                for i := 0; i < alpha; i++ {
+                    yi, okY := borderIndex(border, y0+i, h)
+                    for j := 0; j < alpha; j++ {
+                        xj, okX := borderIndex(border, x0+j, w)
+                        if okY && okX {
+                            d[i*alpha+j] = x[yi*w+xj]
+                        } else {
+                            d[i*alpha+j] = float32(0.0)
+                        }
+                    }
+                }
+            }
+            winogradProduct(params.bt, alpha, alpha, d, tmp, u)
+            for xi := 0; xi < taps; xi++ {
+                v[(xi*channels+c)*nt+p] = u[xi]
+            }
+        }
+    }
+}
+
+// Transforms y[alpha * alpha][channels][nt] back and accumulates
+// tiles [p0, p0+nt) into output
+
+func winogradOutputFloat(
+        params *winogradParams,
+        y []float32,
+        outputData []float32,
+        outputShape []int,
+        channels int,
+        tilesW int,
+        p0 int,
+        nt int) {
+    m := params.m
+    alpha := params.alpha
+    taps := alpha * alpha
+    h := outputShape[0]
+    w := outputShape[1]
+    t := make([]float32, taps)
+    tmp := make([]float32, m*alpha)
+    r := make([]float32, m*m)
+    for z := 0; z < channels; z++ {
+        out := outputData[z*h*w:]
+        for p := 0; p < nt; p++ {
+            for xi := 0; xi < taps; xi++ {
+                t[xi] = y[(xi*channels+z)*nt+p]
+            }
+            winogradProduct(params.at, m, alpha, t, tmp, r)
+            y0 := ((p0 + p) / tilesW) * m
+            x0 := ((p0 + p) % tilesW) * m
+            for i := 0; i < m && y0 + i < h; i++ {
+                for j := 0; j < m && x0 + j < w; j++ {
+                    out[(y0+i)*w+x0+j] += r[i*m+j]
+                }
+            }
+        }
+    }
+}
+
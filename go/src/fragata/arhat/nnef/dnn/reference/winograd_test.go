@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reference
+
+import (
+    "fmt"
+    "testing"
+    "fragata/arhat/nnef/dnn/api"
+)
+
+// Winograd transforms change rounding; results must match direct
+// convolution within relative tolerance of 1e-4
+
+const winogradTolerance = 1.0e-4
+
+func directConv(input *Tensor, filter *Tensor, bias *Tensor, output *Tensor, padding []int, border api.Border) {
+    one := []int{1, 1}
+    kernel := getConvKernelFloat(false, input.rank)
+    convLoopFloat(1, false, input, filter, bias, output, padding, one, one, border, kernel)
+}
+
+func TestWinogradConv(t *testing.T) {
+    // output sizes are not multiples of tile sizes to cover partial tiles
+    tests := []struct {
+        params *winogradParams
+        input []int
+        output []int
+        padding []int
+    }{
+        {winogradF2, []int{2, 8, 9, 7}, []int{2, 12, 9, 7}, []int{1, 1}},
+        {winogradF2, []int{1, 16, 11, 10}, []int{1, 8, 9, 8}, []int{0, 0}},
+        {winogradF4, []int{2, 8, 17, 19}, []int{2, 12, 17, 19}, []int{1, 1}},
+        {winogradF4, []int{1, 16, 20, 21}, []int{1, 8, 18, 19}, []int{0, 0}},
+    }
+    one := []int{1, 1}
+    for _, test := range tests {
+        for _, border := range allBorders {
+            name := fmt.Sprintf("F%d %v, border %d", test.params.m, test.output, border)
+            input := randomTensor(test.input, 1)
+            filter := randomTensor([]int{test.output[1], test.input[1], 3, 3}, 2)
+            bias := randomTensor([]int{1, test.output[1]}, 3)
+            output := zeroTensor(test.output)
+            expect := zeroTensor(test.output)
+            if !useWinograd(false, input, filter, output, one, one) {
+                t.Fatalf("%s: Winograd not used", name)
+            }
+            if getWinogradParams(output) != test.params {
+                t.Fatalf("%s: wrong tile size", name)
+            }
+            Conv(4, false, input, filter, bias, output, test.padding, one, one, border)
+            directConv(input, filter, bias, expect, test.padding, border)
+            checkClose(t, name, output, expect, winogradTolerance)
+        }
+    }
+}
+
+// Filter transform must follow changes of filter data
+
+func TestWinogradFilterCache(t *testing.T) {
+    input := randomTensor([]int{1, 8, 10, 10}, 1)
+    bias := zeroTensor([]int{1, 8})
+    padding := []int{1, 1}
+    one := []int{1, 1}
+    filter := zeroTensor([]int{8, 8, 3, 3})
+    for seed := int64(2); seed < 4; seed++ {
+        Fill(filter, randomTensor(filter.shape, seed).FloatData())
+        output := zeroTensor([]int{1, 8, 10, 10})
+        expect := zeroTensor([]int{1, 8, 10, 10})
+        Conv(1, false, input, filter, bias, output, padding, one, one, api.BorderConstant)
+        directConv(input, filter, bias, expect, padding, api.BorderConstant)
+        checkClose(t, fmt.Sprintf("filled filter %d", seed), output, expect, winogradTolerance)
+    }
+    // filters written by kernels are not cached
+    computed := zeroTensor([]int{8, 8, 3, 3})
+    for seed := int64(4); seed < 6; seed++ {
+        Unary(api.OpNeg, randomTensor(computed.shape, seed), computed)
+        output := zeroTensor([]int{1, 8, 10, 10})
+        expect := zeroTensor([]int{1, 8, 10, 10})
+        Conv(1, false, input, computed, bias, output, padding, one, one, api.BorderConstant)
+        directConv(input, computed, bias, expect, padding, api.BorderConstant)
+        checkClose(t, fmt.Sprintf("computed filter %d", seed), output, expect, winogradTolerance)
+    }
+}
@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// 
+
+package engine
+
+import (
+    "fmt"
+    "strings"
+    "fragata/arhat/nnef/parser/comp"
+    "fragata/arhat/nnef/runtime"
+)
+
+//
+//    CustomOp
+//
+
+//
+// Custom operation bundle
+//
+// Name: name of the operation
+// Fragment: NNEF fragment declaration of the operation
+// Shape: shape inference function
+// Executor: the executor
+//
+type CustomOp struct {
+    Name string
+    Fragment string
+    Shape ShapeFunc
+    Executor runtime.Executor
+}
+
+//
+// Register custom operation bundles
+//
+// Fragment declarations are appended to stdlib on parsing,
+// shape functions and executors are used by InferShapes and Execute.
+//
+// ops: the custom operations
+//
+// return error value or nil; nothing is registered on error
+//
+func(e *Engine) RegisterCustomOps(ops ...*CustomOp) error {
+    names := make(map[string]bool)
+    for _, op := range ops {
+        err := e.checkCustomOp(op, names)
+        if err != nil {
+            return err
+        }
+        names[op.Name] = true
+    }
+    for _, op := range ops {
+        e.customExecutors[op.Name] = op.Executor
+        e.customFragments = append(e.customFragments, op.Fragment)
+        e.customShapes[op.Name] = op.Shape
+    }
+    return nil
+}
+
+func(e *Engine) checkCustomOp(op *CustomOp, names map[string]bool) error {
+    name := op.Name
+    if _, ok := StandardShapeFuncs[name]; ok || runtime.FindExecutor(name) != nil {
+        return fmt.Errorf("Standard operation '%s' cannot be replaced", name)
+    }
+    if _, ok := e.customShapes[name]; ok || names[name] {
+        return fmt.Errorf("Custom operation '%s' is already registered", name)
+    }
+    if op.Fragment == "" || op.Shape == nil || op.Executor == nil {
+        return fmt.Errorf("Incomplete definition of custom operation '%s'", name)
+    }
+    return nil
+}
+
+func(e *Engine) extendStdlib(stdlib string) string {
+    if len(e.customFragments) == 0 {
+        return stdlib
+    }
+    if stdlib == "" {
+        stdlib = comp.StdlibSource()
+    }
+    return stdlib + "\n" + strings.Join(e.customFragments, "\n") + "\n"
+}
+
@@ -49,15 +49,54 @@ type ShapeFunc func(op *core.Operation, graph *core.Graph)
 type Engine struct {
     dnn dnn.Engine
     contexts map[*core.Graph]*runtime.Context
+    plans map[*core.Graph]*MemoryPlan
+    customExecutors map[string]runtime.Executor
+    customShapes map[string]ShapeFunc
+    customFragments []string
+    autoLowering bool
 }
 
 func NewEngine(dnnEngine dnn.Engine) *Engine {
     e := new(Engine)
     e.dnn = dnnEngine
     e.contexts = make(map[*core.Graph]*runtime.Context)
+    e.plans = make(map[*core.Graph]*MemoryPlan)
+    e.customExecutors = make(map[string]runtime.Executor)
+    e.customShapes = make(map[string]ShapeFunc)
     return e
 }
 
+//
+// Register executor for a custom operation
+//
+// name: name of the operation
+// fn: the executor; pairs with shape function passed 
+//     to InferShapes via customShapes
+//
+// return error value or nil
+//
+func(e *Engine) RegisterExecutor(name string, fn runtime.Executor) error {
+    if runtime.FindExecutor(name) != nil {
+        return fmt.Errorf("Executor for standard operation '%s' cannot be replaced", name)
+    }
+    e.customExecutors[name] = fn
+    return nil
+}
+
+//
+// Enable derivation of lowered operations from backend capabilities
+//
+// When enabled, ParseFile, ParseString and LoadGraph called with
+// nil lowered set lower standard operations that have no executor
+// or that the backend does not support, including operations whose
+// attributes select unsupported variants. Disabled by default.
+//
+// enable: whether to derive lowered operations
+//
+func(e *Engine) SetAutoLowering(enable bool) {
+    e.autoLowering = enable
+}
+
 //
 // Parse the NNEF graph from file
 //
@@ -65,7 +104,8 @@ func NewEngine(dnnEngine dnn.Engine) *Engine {
 // quantFn: name of the quantization file
 // graph: the graph data structure to fill in
 // stdlib: the implementation of standard operations to use
-// lowered: a list of operations to be lowered
+// lowered: a list of operations to be lowered; if nil and
+//     auto lowering is enabled, derived from backend capabilities
 //
 // return error value or nil
 //
@@ -75,6 +115,21 @@ func(e *Engine) ParseFile(
         graph *core.Graph,
         stdlib string,
         lowered map[string]bool) error {
+    stdlib = e.extendStdlib(stdlib)
+    if lowered == nil && e.autoLowering {
+        return e.parseLowering(graph, func(graph *core.Graph, lowered map[string]bool) error {
+            return parseFile(graphFn, quantFn, graph, stdlib, lowered)
+        })
+    }
+    return parseFile(graphFn, quantFn, graph, stdlib, lowered)
+}
+
+func parseFile(
+        graphFn string,
+        quantFn string,
+        graph *core.Graph,
+        stdlib string,
+        lowered map[string]bool) error {
     graphIs, err := os.Open(graphFn)
     if err != nil {
         return fmt.Errorf("Could not open graph file: %s", graphFn)
@@ -98,7 +153,8 @@ func(e *Engine) ParseFile(
 // quantStr: the quantization string
 // graph: the graph data structure to fill in
 // stdlib: the implementation of standard operations to use
-// lowered: a list of operations to be lowered
+// lowered: a list of operations to be lowered; if nil and
+//     auto lowering is enabled, derived from backend capabilities
 //
 // return error value or nil
 //
@@ -108,12 +164,90 @@ func(e *Engine) ParseString(
         graph *core.Graph, 
         stdlib string, 
         lowered map[string]bool) error {
+    stdlib = e.extendStdlib(stdlib)
+    if lowered == nil && e.autoLowering {
+        return e.parseLowering(graph, func(graph *core.Graph, lowered map[string]bool) error {
+            return parseString(graphStr, quantStr, graph, stdlib, lowered)
+        })
+    }
+    return parseString(graphStr, quantStr, graph, stdlib, lowered)
+}
+
+func parseString(
+        graphStr string, 
+        quantStr string,
+        graph *core.Graph, 
+        stdlib string, 
+        lowered map[string]bool) error {
     graphIs := strings.NewReader(graphStr)
     var quantIs *strings.Reader
     if quantStr != "" {
         quantIs = strings.NewReader(quantStr)
     }
-    return parse(graphIs, "input", quantIs, "quantization", graph, stdlib, lowered)
+    return parse(
+        graphIs, 
+        "input", 
+        quantIs, 
+        "quantization", 
+        graph, 
+        stdlib, 
+        lowered)
+}
+
+// Parsing is repeated while graph contains operations in variants
+// not supported by backend: these are lowered too if possible.
+
+func(e *Engine) parseLowering(
+        graph *core.Graph, 
+        parse func(graph *core.Graph, lowered map[string]bool) error) error {
+    lowered := e.LoweredOps()
+    for {
+        result := new(core.Graph)
+        err := parse(result, lowered)
+        if err != nil {
+            return err
+        }
+        op := e.findUnsupportedOp(result)
+        if op == nil {
+            *graph = *result
+            return nil
+        }
+        if lowered[op.Name()] {
+            return fmt.Errorf(
+                "Operation '%s' with attributes %v is not supported by backend " +
+                    "and cannot be lowered", op.Name(), variantAttribs(op))
+        }
+        lowered[op.Name()] = true
+    }
+}
+
+func(e *Engine) findUnsupportedOp(graph *core.Graph) *core.Operation {
+    count := graph.OperationCount()
+    for i := 0; i < count; i++ {
+        op := graph.OperationAt(i)
+        name := op.Name()
+        if _, ok := StandardShapeFuncs[name]; !ok {
+            continue
+        }
+        if !dnn.Supports(e.dnn, name, variantAttribs(op)) {
+            return op
+        }
+    }
+    return nil
+}
+
+// Variants are selected by string attributes
+
+func variantAttribs(op *core.Operation) map[string]string {
+    attribs := make(map[string]string)
+    count := op.AttribCount()
+    for i := 0; i < count; i++ {
+        value := op.AttribAt(i)
+        if value.Kind() == core.ValueKindString {
+            attribs[op.AttribNameAt(i)] = value.String()
+        }
+    }
+    return attribs
 }
 
 func parse(
@@ -353,7 +487,8 @@ func valueToShape(value core.Value) core.Shape {
 // path: the path to the top level NNEF model folder
 // graph: the graph object to load tensors into
 // stdlib: the implementation of standard operations to use
-// lowered: a list of operations to be lowered
+// lowered: a list of operations to be lowered; if nil and
+//     auto lowering is enabled, derived from backend capabilities
 //
 // return error value or nil
 //
@@ -385,6 +520,26 @@ func(e *Engine) LoadGraph(
     return nil
 }
 
+//
+// Compute operations to be lowered for the attached backend
+//
+// return set of standard operations that have no executor
+//     or are not supported natively by the backend;
+//     empty if no backend is attached
+//
+func(e *Engine) LoweredOps() map[string]bool {
+    lowered := make(map[string]bool)
+    if e.dnn == nil {
+        return lowered
+    }
+    for name := range StandardShapeFuncs {
+        if e.findExecutor(name) == nil || !dnn.Supports(e.dnn, name, nil) {
+            lowered[name] = true
+        }
+    }
+    return lowered
+}
+
 func fileExists(path string) bool {
     info, err := os.Stat(path)
     return (err == nil && info.Mode().IsRegular())
@@ -482,9 +637,9 @@ var StandardShapeFuncs = map[string]ShapeFunc{
         
     "avg_roi_pool": RoiShapeFunc,
     "max_roi_pool": RoiShapeFunc,
-    "avg_roi_align": RoiShapeFunc,
-    "max_roi_align": RoiShapeFunc,
-    "roi_resample": RoiShapeResampleFunc,
+    "avg_roi_align": RoiShapeResampleFunc,
+    "max_roi_align": RoiShapeResampleFunc,
+    "roi_resample": RoiShapeFunc,
         
     "reshape": ReshapeShapeFunc,
     "transpose": TransposeShapeFunc,
@@ -513,6 +668,7 @@ var StandardShapeFuncs = map[string]ShapeFunc{
 // graph: the graph object
 // inputShapes: shapes of external tensors
 // customShapes: shape inference functions for custom operations
+//     (bundles registered via RegisterCustomOps are used as well)
 //
 // return error value or nil
 //
@@ -527,6 +683,9 @@ func(e *Engine) InferShapes(
         fn, ok := StandardShapeFuncs[name]
         if !ok {
             fn, ok = customShapes[name]
+        }
+        if !ok {
+            fn, ok = e.customShapes[name]
             if !ok {
                 return fmt.Errorf("Shape function for operation '%s' is not provided", name)
             }
@@ -600,7 +759,10 @@ func(e *Engine) Execute(graph *core.Graph) (err error) {
     if !ok {
         ctx = runtime.NewContext(graph, e.dnn)
         e.contexts[graph] = ctx
-        createTensors(graph, ctx)
+        plan := allocateTensors(graph, ctx)
+        if plan != nil {
+            e.plans[graph] = plan
+        }
         writeVariables(graph, ctx)
     }
     writeInputs(graph, ctx)
@@ -608,7 +770,7 @@ func(e *Engine) Execute(graph *core.Graph) (err error) {
     for i := 0; i < opCount; i++ {
         op := graph.OperationAt(i)
         name := op.Name()
-        fn := runtime.FindExecutor(name)
+        fn := e.findExecutor(name)
         if fn == nil {
             return fmt.Errorf("operation not implemented: %s", name)
         }
@@ -620,11 +782,12 @@ func(e *Engine) Execute(graph *core.Graph) (err error) {
     return
 }
 
-func createTensors(graph *core.Graph, ctx *runtime.Context) {
-    count := graph.TensorCount()
-    for i := 0; i < count; i++ {
-        ctx.CreateTensor(graph.TensorAt(i))
+func(e *Engine) findExecutor(name string) runtime.Executor {
+    fn := runtime.FindExecutor(name)
+    if fn == nil {
+        fn = e.customExecutors[name]
     }
+    return fn
 }
 
 func writeVariables(graph *core.Graph, ctx *runtime.Context) {
@@ -676,6 +839,7 @@ func checkData(tensor *core.Tensor) {
 //
 func(e *Engine) Release(graph *core.Graph) error {
     delete(e.contexts, graph)
+    delete(e.plans, graph)
     return nil
 }
 
@@ -0,0 +1,253 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package engine
+
+import (
+    "sort"
+    "fragata/arhat/nnef/core"
+    dnn "fragata/arhat/nnef/dnn/api"
+    "fragata/arhat/nnef/runtime"
+)
+
+//
+//    MemoryPlan
+//
+
+//
+// Assignment of graph tensors to memory
+//
+// Lifetime of a tensor spans operations from the first to the last one
+// that refers to it. Graph inputs, outputs and variables are pinned and
+// get storage of their own; other tensors of the same dtype whose lifetimes
+// do not overlap share buffers. Sizes are those of storage allocated
+// by dnn engine.
+//
+// PeakBytes: planned peak memory, largest size of storage in use during
+//     any operation; tensors allocated separately are always in use,
+//     shared buffers while they hold a live tensor
+// SeparateBytes: size of tensors allocated separately
+// SharedBytes: size of shared buffers
+// UnplannedBytes: size of all tensors if each were allocated separately
+//
+type MemoryPlan struct {
+    PeakBytes int
+    SeparateBytes int
+    SharedBytes int
+    UnplannedBytes int
+    buffers []*memoryBuffer
+    assignment map[*core.Tensor]int
+    first map[*core.Tensor]int
+    last map[*core.Tensor]int
+}
+
+type memoryBuffer struct {
+    dtype string
+    volume int
+    busyUntil int
+}
+
+//
+// Total memory allocated for tensors of the graph
+//
+func(p *MemoryPlan) AllocatedBytes() int {
+    return p.SeparateBytes + p.SharedBytes
+}
+
+//
+// Memory plan used by runtime context of a graph
+//
+// graph: the graph object
+//
+// return the plan or nil if the graph has not been executed yet
+//     or dnn engine has no shared storage
+//
+func(e *Engine) MemoryPlan(graph *core.Graph) *MemoryPlan {
+    return e.plans[graph]
+}
+
+// Assigns shared buffers to tensors that are neither pinned nor unused
+
+func planMemory(graph *core.Graph) *MemoryPlan {
+    p := new(MemoryPlan)
+    p.assignment = make(map[*core.Tensor]int)
+    first, last := tensorLifetimes(graph)
+    p.first = first
+    p.last = last
+    pinned := pinnedTensors(graph)
+    var shared []*core.Tensor
+    count := graph.TensorCount()
+    for i := 0; i < count; i++ {
+        tensor := graph.TensorAt(i)
+        _, used := first[tensor]
+        if used && !pinned[tensor] {
+            shared = append(shared, tensor)
+        }
+    }
+    sort.SliceStable(shared, func(i int, j int) bool {
+        return first[shared[i]] < first[shared[j]]
+    })
+    for _, tensor := range shared {
+        k := p.findBuffer(tensor, first[tensor])
+        buffer := p.buffers[k]
+        volume := core.Shape(tensor.Shape()).VolumeOf()
+        if volume > buffer.volume {
+            buffer.volume = volume
+        }
+        buffer.busyUntil = last[tensor]
+        p.assignment[tensor] = k
+    }
+    return p
+}
+
+// Selects free buffer of tensor dtype: the smallest one that fits,
+// otherwise the largest one to minimize growth; creates new buffer
+// if there are no free ones.
+
+func(p *MemoryPlan) findBuffer(tensor *core.Tensor, start int) int {
+    dtype := tensor.Dtype()
+    volume := core.Shape(tensor.Shape()).VolumeOf()
+    best := -1
+    for k, buffer := range p.buffers {
+        if buffer.dtype != dtype || buffer.busyUntil >= start {
+            continue
+        }
+        if best < 0 {
+            best = k
+            continue
+        }
+        fits := (buffer.volume >= volume)
+        bestVolume := p.buffers[best].volume
+        bestFits := (bestVolume >= volume)
+        switch {
+        case fits && (!bestFits || buffer.volume < bestVolume):
+            best = k
+        case !fits && !bestFits && buffer.volume > bestVolume:
+            best = k
+        }
+    }
+    if best < 0 {
+        best = len(p.buffers)
+        p.buffers = append(p.buffers, &memoryBuffer{dtype: dtype, busyUntil: -1})
+    }
+    return best
+}
+
+func tensorLifetimes(graph *core.Graph) (map[*core.Tensor]int, map[*core.Tensor]int) {
+    first := make(map[*core.Tensor]int)
+    last := make(map[*core.Tensor]int)
+    var visit func(value core.Value, step int)
+    visit = func(value core.Value, step int) {
+        switch value.Kind() {
+        case core.ValueKindIdentifier:
+            tensor := graph.GetTensor(value.Identifier())
+            if tensor == nil {
+                return
+            }
+            if _, ok := first[tensor]; !ok {
+                first[tensor] = step
+            }
+            last[tensor] = step
+        case core.ValueKindArray, core.ValueKindTuple:
+            size := value.Size()
+            for i := 0; i < size; i++ {
+                visit(value.At(i), step)
+            }
+        }
+    }
+    count := graph.OperationCount()
+    for i := 0; i < count; i++ {
+        op := graph.OperationAt(i)
+        inputCount := op.InputCount()
+        for k := 0; k < inputCount; k++ {
+            visit(op.InputAt(k), i)
+        }
+        outputCount := op.OutputCount()
+        for k := 0; k < outputCount; k++ {
+            visit(op.OutputAt(k), i)
+        }
+    }
+    return first, last
+}
+
+func pinnedTensors(graph *core.Graph) map[*core.Tensor]bool {
+    pinned := make(map[*core.Tensor]bool)
+    count := graph.InputCount()
+    for i := 0; i < count; i++ {
+        pinned[graph.GetTensor(graph.InputAt(i))] = true
+    }
+    count = graph.OutputCount()
+    for i := 0; i < count; i++ {
+        pinned[graph.GetTensor(graph.OutputAt(i))] = true
+    }
+    count = graph.OperationCount()
+    for i := 0; i < count; i++ {
+        op := graph.OperationAt(i)
+        if op.Name() == "variable" {
+            pinned[graph.GetTensor(op.OutputAt(0).Identifier())] = true
+        }
+    }
+    return pinned
+}
+
+// Tensors are allocated separately and no plan is made
+// if dnn engine has no shared storage
+
+func allocateTensors(graph *core.Graph, ctx *runtime.Context) *MemoryPlan {
+    count := graph.TensorCount()
+    if !ctx.HasBuffers() {
+        for i := 0; i < count; i++ {
+            ctx.CreateTensor(graph.TensorAt(i))
+        }
+        return nil
+    }
+    plan := planMemory(graph)
+    buffers := make([]dnn.Buffer, len(plan.buffers))
+    bufferBytes := make([]int, len(plan.buffers))
+    for k, buffer := range plan.buffers {
+        buffers[k] = ctx.NewBuffer(buffer.dtype, buffer.volume)
+        bufferBytes[k] = buffers[k].Volume() * ctx.ItemSize(buffer.dtype)
+        plan.SharedBytes += bufferBytes[k]
+    }
+    // change of shared storage in use at each step; tensors
+    // assigned to the same buffer have disjoint lifetimes
+    steps := graph.OperationCount()
+    delta := make([]int, steps+1)
+    for i := 0; i < count; i++ {
+        tensor := graph.TensorAt(i)
+        bytes := core.Shape(tensor.Shape()).VolumeOf() * ctx.ItemSize(tensor.Dtype())
+        plan.UnplannedBytes += bytes
+        if k, ok := plan.assignment[tensor]; ok {
+            ctx.CreateTensorIn(tensor, buffers[k])
+            delta[plan.first[tensor]] += bufferBytes[k]
+            delta[plan.last[tensor]+1] -= bufferBytes[k]
+        } else {
+            ctx.CreateTensor(tensor)
+            plan.SeparateBytes += bytes
+        }
+    }
+    live := 0
+    peak := 0
+    for i := 0; i < steps; i++ {
+        live += delta[i]
+        if live > peak {
+            peak = live
+        }
+    }
+    plan.PeakBytes = plan.SeparateBytes + peak
+    return plan
+}
+
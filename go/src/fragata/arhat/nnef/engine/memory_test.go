@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2019-2020 FRAGATA COMPUTER SYSTEMS AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package engine
+
+import (
+    "testing"
+    "unsafe"
+    "fragata/arhat/nnef/core"
+    dnn "fragata/arhat/nnef/dnn/api"
+    "fragata/arhat/nnef/dnn/reference"
+)
+
+// Lifetimes: a [0, 1], b [1, 2], c [2, 5], i [3, 4]; c reuses buffer of a.
+// Integer buffer of i is in use only while buffer of b is free.
+
+const memoryTestGraph = `
+version 1.0;
+
+graph G( input ) -> ( output, index )
+{
+    input = external<scalar>(shape = [1, 4, 8, 8]);
+    a = relu(input);
+    b = neg(a);
+    c = exp(b);
+    i = argmax_reduce(c, axes = [1]);
+    index = copy(i);
+    output = relu(c);
+}
+`
+
+// Hides BufferAllocator of wrapped engine
+
+type unsharedEngine struct {
+    dnn.Engine
+}
+
+func runMemoryTestGraph(t *testing.T, dnnEngine dnn.Engine) (*core.Graph, *MemoryPlan) {
+    t.Helper()
+    e := NewEngine(dnnEngine)
+    graph := new(core.Graph)
+    // quantization string must not be empty
+    err := e.ParseString(memoryTestGraph, "\n", graph, "", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = e.InferShapes(graph, nil, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    input := graph.GetTensor("input")
+    input.ResizeData()
+    data := input.ScalarData()
+    for i := range data {
+        data[i] = float32(i % 7) * 0.25 - 0.75
+    }
+    err = e.Execute(graph)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return graph, e.MemoryPlan(graph)
+}
+
+func TestMemoryPlanResults(t *testing.T) {
+    shared, _ := runMemoryTestGraph(t, reference.NewEngine(1))
+    unshared, plan := runMemoryTestGraph(t, &unsharedEngine{reference.NewEngine(1)})
+    if plan != nil {
+        t.Fatalf("plan reported for engine without shared storage")
+    }
+    x := shared.GetTensor("output").ScalarData()
+    y := unshared.GetTensor("output").ScalarData()
+    for i := range x {
+        if x[i] != y[i] {
+            t.Fatalf("output item %d differs: %g != %g", i, x[i], y[i])
+        }
+    }
+    p := shared.GetTensor("index").IntegerData()
+    q := unshared.GetTensor("index").IntegerData()
+    for i := range p {
+        if p[i] != q[i] {
+            t.Fatalf("index item %d differs: %d != %d", i, p[i], q[i])
+        }
+    }
+}
+
+func TestMemoryPlanSizes(t *testing.T) {
+    _, plan := runMemoryTestGraph(t, reference.NewEngine(1))
+    if plan == nil {
+        t.Fatalf("no plan reported")
+    }
+    scalarBytes := 256 * int(unsafe.Sizeof(float32(0.0)))
+    indexBytes := 64 * int(unsafe.Sizeof(int(0)))
+    checkBytes(t, "shared", plan.SharedBytes, 2 * scalarBytes + indexBytes)
+    checkBytes(t, "separate", plan.SeparateBytes, 2 * scalarBytes + indexBytes)
+    checkBytes(t, "peak", plan.PeakBytes, 4 * scalarBytes + indexBytes)
+    checkBytes(t, "unplanned", plan.UnplannedBytes, 5 * scalarBytes + 2 * indexBytes)
+}
+
+func checkBytes(t *testing.T, name string, bytes int, expect int) {
+    t.Helper()
+    if bytes != expect {
+        t.Errorf("%s bytes: %d, expected %d", name, bytes, expect)
+    }
+}
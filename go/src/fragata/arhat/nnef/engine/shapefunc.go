@@ -257,7 +257,7 @@ func BatchnormShapeFunc(op *core.Operation, graph *core.Graph) {
 }
 
 func RoiShapeFunc(op *core.Operation, graph *core.Graph) {
-    // [3] "sampling_rate" [4] "resize_method" not used
+    // only "output_size" (attrib 0) is used
     shape :=
         core.RoiShape(
             InputShape(graph, op, 0),
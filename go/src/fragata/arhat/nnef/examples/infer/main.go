@@ -28,33 +28,13 @@ import (
     "math"
     "math/rand"
     "os"
+    "strconv"
     "strings"
     "fragata/arhat/nnef/core"
     "fragata/arhat/nnef/dnn/reference"
     "fragata/arhat/nnef/engine"
 )
 
-var lowered = map[string]bool {
-    "separable_conv": true,
-    "separable_deconv": true,
-    "rms_pool": true,
-    "local_response_normalization": true,
-    "local_mean_normalization": true,
-    "local_variance_normalization": true,
-    "local_contrast_normalization": true,
-    "l1_normalization": true,
-    "l2_normalization": true,
-    "batch_normalization": true,
-    "area_downsample": true,
-    "nearest_downsample": true,
-    "nearest_upsample": true,
-    "linear_quantize": true,
-    "logarithmic_quantize": true,
-    "leaky_relu": true,
-    "prelu": true,
-    "clamp": true,
-}
-
 func main() {
     argv := os.Args
     argc := len(argv) 
@@ -68,6 +48,8 @@ func main() {
     var inputs []string
     var outputs []string
     compare := false
+    memory := false
+    workers := 0
     for i := 2; i < argc; i++ {
         arg := argv[i]
         switch arg {
@@ -101,14 +83,29 @@ func main() {
             }
         case "--compare":
             compare = true
+        case "--memory":
+            memory = true
+        case "--workers":
+            i++
+            if i == argc {
+                fmt.Fprintf(os.Stderr, 
+                    "Worker count must be provided after --workers; ignoring option\n")
+                break
+            }
+            workers, err = strconv.Atoi(argv[i])
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "Invalid worker count: '%s'; ignoring option\n", argv[i])
+                workers = 0
+            }
         default:
             fmt.Fprintf(os.Stderr, "Unrecognized option: '%s'; ignoring\n", argv[i])
         }
     }
-    dnn := reference.NewEngine()
+    dnn := reference.NewEngine(workers)
     nnef := engine.NewEngine(dnn)
+    nnef.SetAutoLowering(true)
     graph := new(core.Graph)
-    err = nnef.LoadGraph(path, graph, stdlib, lowered)
+    err = nnef.LoadGraph(path, graph, stdlib, nil)
     if  err != nil {
         signalError(err)
     }
@@ -139,6 +136,9 @@ func main() {
     if err != nil {
         signalError(err)
     }
+    if memory {
+        printMemoryPlan(nnef.MemoryPlan(graph))
+    }
     if len(outputs) != 0 {
         if compare {
             count := graph.OutputCount()
@@ -187,6 +187,16 @@ func main() {
     printClasses(graph, 3)
 }
 
+func printMemoryPlan(plan *engine.MemoryPlan) {
+    if plan == nil {
+        fmt.Fprintf(os.Stderr, "Memory planning is not supported by backend\n")
+        return
+    }
+    fmt.Fprintf(os.Stderr, 
+        "Planned peak memory: %d bytes (%d without planning, %d allocated in total)\n",
+        plan.PeakBytes, plan.UnplannedBytes, plan.AllocatedBytes())
+}
+
 func readFile(fn string) (string, error) {
     buf, err := ioutil.ReadFile(fn)
     if err != nil {
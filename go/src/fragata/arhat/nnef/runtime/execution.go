@@ -54,18 +54,7 @@ func(c *Context) CreateTensor(tensor *core.Tensor) {
     if ok {
         core.RuntimeError("Tensor already exists: '%s'", tensor.Name())
     }
-    var t dnn.Dtype
-    dtype := tensor.Dtype()
-    switch dtype {
-    case "scalar":
-        t = dnn.DtypeFloat
-    case "integer":
-        t = dnn.DtypeInt
-    case "logical":
-        t = dnn.DtypeBool
-    default:
-         core.RuntimeError("data type not supported: %s", dtype)
-    }
+    t := mapDtype(tensor.Dtype())
     view, err := c.dnn.NewTensor(t, tensor.Shape())
     if err != nil {
         signalError(err)
@@ -73,6 +62,53 @@ func(c *Context) CreateTensor(tensor *core.Tensor) {
     c.tensorMap[tensor] = view    
 }
 
+// Reports whether dnn engine supports shared storage
+
+func(c *Context) HasBuffers() bool {
+    _, ok := c.dnn.(dnn.BufferAllocator)
+    return ok
+}
+
+// Size in bytes of item of given NNEF dtype in storage of dnn engine;
+// returns 0 if dnn engine has no shared storage.
+
+func(c *Context) ItemSize(dtype string) int {
+    allocator, ok := c.dnn.(dnn.BufferAllocator)
+    if !ok {
+        return 0
+    }
+    return allocator.ItemSize(mapDtype(dtype))
+}
+
+// Allocates buffer for tensors of given NNEF dtype to be created
+// by CreateTensorIn; returns nil if dnn engine has no shared storage.
+
+func(c *Context) NewBuffer(dtype string, volume int) dnn.Buffer {
+    allocator, ok := c.dnn.(dnn.BufferAllocator)
+    if !ok {
+        return nil
+    }
+    buffer, err := allocator.NewBuffer(mapDtype(dtype), volume)
+    if err != nil {
+        signalError(err)
+    }
+    return buffer
+}
+
+func(c *Context) CreateTensorIn(tensor *core.Tensor, buffer dnn.Buffer) {
+    view, ok := c.tensorMap[tensor]
+    if ok {
+        core.RuntimeError("Tensor already exists: '%s'", tensor.Name())
+    }
+    core.Assert(buffer.Dtype() == mapDtype(tensor.Dtype()))
+    allocator := c.dnn.(dnn.BufferAllocator)
+    view, err := allocator.NewTensorIn(buffer, tensor.Shape())
+    if err != nil {
+        signalError(err)
+    }
+    c.tensorMap[tensor] = view
+}
+
 func(c *Context) WriteTensor(tensor *core.Tensor) {
     view := c.MapTensor(tensor)
     err := c.dnn.Fill(view, tensor.Data())
@@ -97,8 +133,37 @@ func(c *Context) MapTensor(tensor *core.Tensor) dnn.Tensor {
     return view
 }
 
+// Maps operation argument to tensor view: identifiers refer
+// to graph tensors, literals are converted to singleton tensors.
+
+func(c *Context) MapValue(t dnn.Dtype, value core.Value) dnn.Tensor {
+    return mapTensor(c, t, value)
+}
+
+func(c *Context) Graph() *core.Graph {
+    return c.graph
+}
+
+func(c *Context) Dnn() dnn.Engine {
+    return c.dnn
+}
+
 // implementation
 
+func mapDtype(dtype string) dnn.Dtype {
+    switch dtype {
+    case "scalar":
+        return dnn.DtypeFloat
+    case "integer":
+        return dnn.DtypeInt
+    case "logical":
+        return dnn.DtypeBool
+    default:
+        core.RuntimeError("data type not supported: %s", dtype)
+        return 0
+    }
+}
+
 func(c *Context) getTensor(name string) dnn.Tensor {
     tensor := c.graph.GetTensor(name)
     core.Assert(tensor != nil)
@@ -109,6 +174,9 @@ func(c *Context) getTensor(name string) dnn.Tensor {
 //    Executor
 //
 
+// Executors signal errors by panicking with error value,
+// for example via core.RuntimeError.
+
 type Executor func(ctx *Context, op *core.Operation)
 
 func FindExecutor(name string) Executor {
@@ -128,48 +196,52 @@ var executorMap = map[string]Executor {
     "constant": executeConstant,
     "variable": executeVariable,
         
-    "neg": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpNeg),
-    "not": makeUnaryExecutor(dnn.DtypeBool, dnn.OpNot),
-    "abs": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpAbs),
-    "sign": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSign),
-    "exp": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpExp),
-    "log": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpLog),
-    "log2": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpLog2),
-    "sin": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSin),
-    "cos": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpCos),
-    "round": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpRound),
-    "floor": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpFloor),
-    "ceil": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpCeil),
-    "sqrt": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSqrt),
-    "sqr": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSqr),
-    "rsqrt": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpRsqrt),
-    "rsqr": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpRsqr),
-    "rcp": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpRcp),
-    "copy": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpCopy),
-
-    "sigmoid": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSigmoid),
-    "tanh": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpTanh),
-    "relu": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpRelu),
-    "elu": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpElu),
-    "softplus": makeUnaryExecutor(dnn.DtypeFloat, dnn.OpSoftplus),
-
-    "add": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpAdd),
-    "sub": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpSub),
-    "mul": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpMul),
-    "div": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpDiv),
-    "pow": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpPow),
-    "min": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpMin),
-    "max": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeFloat, dnn.OpMax),
-    "and": makeBinaryExecutor(dnn.DtypeBool, dnn.DtypeBool, dnn.OpAnd),
-    "or": makeBinaryExecutor(dnn.DtypeBool, dnn.DtypeBool, dnn.OpOr),
-    "lt": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpLt),
-    "gt": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpGt),
-    "le": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpLe),
-    "ge": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpGe),
-    "eq": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpEq),
-    "ne": makeBinaryExecutor(dnn.DtypeFloat, dnn.DtypeBool, dnn.OpNe),
+    "neg": makeUnaryExecutor(dnn.OpNeg),
+    "not": makeUnaryExecutor(dnn.OpNot),
+    "abs": makeUnaryExecutor(dnn.OpAbs),
+    "sign": makeUnaryExecutor(dnn.OpSign),
+    "exp": makeUnaryExecutor(dnn.OpExp),
+    "log": makeUnaryExecutor(dnn.OpLog),
+    "log2": makeUnaryExecutor(dnn.OpLog2),
+    "sin": makeUnaryExecutor(dnn.OpSin),
+    "cos": makeUnaryExecutor(dnn.OpCos),
+    "round": makeUnaryExecutor(dnn.OpRound),
+    "floor": makeUnaryExecutor(dnn.OpFloor),
+    "ceil": makeUnaryExecutor(dnn.OpCeil),
+    "sqrt": makeUnaryExecutor(dnn.OpSqrt),
+    "sqr": makeUnaryExecutor(dnn.OpSqr),
+    "rsqrt": makeUnaryExecutor(dnn.OpRsqrt),
+    "rsqr": makeUnaryExecutor(dnn.OpRsqr),
+    "rcp": makeUnaryExecutor(dnn.OpRcp),
+    "copy": makeUnaryExecutor(dnn.OpCopy),
+
+    "sigmoid": makeUnaryExecutor(dnn.OpSigmoid),
+    "tanh": makeUnaryExecutor(dnn.OpTanh),
+    "relu": makeUnaryExecutor(dnn.OpRelu),
+    "elu": makeUnaryExecutor(dnn.OpElu),
+    "leaky_relu": makeUnaryParamExecutor(dnn.DtypeFloat, dnn.OpLeakyRelu, "alpha"),
+    "softabs": makeUnaryParamExecutor(dnn.DtypeFloat, dnn.OpSoftabs, "epsilon"),
+    "prelu": executePrelu,
+    "softplus": makeUnaryExecutor(dnn.OpSoftplus),
+
+    "add": makeBinaryExecutor(dnn.OpAdd),
+    "sub": makeBinaryExecutor(dnn.OpSub),
+    "mul": makeBinaryExecutor(dnn.OpMul),
+    "div": makeBinaryExecutor(dnn.OpDiv),
+    "pow": makeBinaryExecutor(dnn.OpPow),
+    "min": makeBinaryExecutor(dnn.OpMin),
+    "max": makeBinaryExecutor(dnn.OpMax),
+    "and": makeBinaryExecutor(dnn.OpAnd),
+    "or": makeBinaryExecutor(dnn.OpOr),
+    "lt": makeBinaryExecutor(dnn.OpLt),
+    "gt": makeBinaryExecutor(dnn.OpGt),
+    "le": makeBinaryExecutor(dnn.OpLe),
+    "ge": makeBinaryExecutor(dnn.OpGe),
+    "eq": makeBinaryExecutor(dnn.OpEq),
+    "ne": makeBinaryExecutor(dnn.OpNe),
 
     "select": executeSelect,
+    "clamp": executeClamp,
 
     "sum_reduce": makeReduceExecutor(dnn.DtypeFloat, dnn.OpSumReduce),
     "mean_reduce": makeReduceExecutor(dnn.DtypeFloat, dnn.OpMeanReduce),
@@ -177,6 +249,7 @@ var executorMap = map[string]Executor {
     "max_reduce": makeReduceExecutor(dnn.DtypeFloat, dnn.OpMaxReduce),
     "any_reduce": makeReduceExecutor(dnn.DtypeBool, dnn.OpAnyReduce),
     "all_reduce": makeReduceExecutor(dnn.DtypeBool, dnn.OpAllReduce),
+    "moments": executeMoments,
 
     "conv": makeConvExecutor(false, dnn.DtypeFloat),
     "deconv": makeConvExecutor(true, dnn.DtypeFloat),
@@ -185,6 +258,10 @@ var executorMap = map[string]Executor {
     "debox": makePoolExecutor(true, dnn.DtypeFloat),
     "avg_pool": makePoolExecutor(false, dnn.DtypeFloat),
     "max_pool": makePoolExecutor(false, dnn.DtypeFloat),
+    "argmax_pool": executeMaxPoolWithIndex,
+    "max_pool_with_index": executeMaxPoolWithIndex,
+    "sample": makeSampleExecutor(false, dnn.DtypeFloat),
+    "desample": makeSampleExecutor(true, dnn.DtypeFloat),
 
     "reshape": executeReshape,
     "squeeze": executeReshape,
@@ -195,10 +272,27 @@ var executorMap = map[string]Executor {
     "split": executeSplit,
     "stack": executeConcat,
     "unstack": executeSplit,
-    "pad": makePadExecutor(dnn.DtypeFloat),
+    "pad": executePad,
+    "copy_n": executeCopyN,
+    "add_n": executeAddN,
     "tile": executeTile,
     "slice": executeSlice,
 
+    "local_response_normalization": 
+        makeLocalNormExecutor(dnn.DtypeFloat, dnn.OpLocalResponseNorm),
+    "local_mean_normalization": 
+        makeLocalNormExecutor(dnn.DtypeFloat, dnn.OpLocalMeanNorm),
+    "local_variance_normalization": 
+        makeLocalNormExecutor(dnn.DtypeFloat, dnn.OpLocalVarianceNorm),
+    "local_contrast_normalization": 
+        makeLocalNormExecutor(dnn.DtypeFloat, dnn.OpLocalContrastNorm),
+    "l1_normalization": makeNormExecutor(dnn.DtypeFloat, dnn.OpL1Norm),
+    "l2_normalization": makeNormExecutor(dnn.DtypeFloat, dnn.OpL2Norm),
+    "batch_normalization": makeBatchNormExecutor(dnn.DtypeFloat),
+
+    "linear_quantize": makeLinearQuantizeExecutor(dnn.DtypeFloat),
+    "logarithmic_quantize": makeLogarithmicQuantizeExecutor(dnn.DtypeFloat),
+
     "matmul": makeMatmulExecutor(dnn.DtypeFloat),
     "linear": makeLinearExecutor(dnn.DtypeFloat),
         
@@ -207,6 +301,12 @@ var executorMap = map[string]Executor {
     "argmax_reduce": makeArgReduceExecutor(dnn.DtypeFloat, dnn.DtypeInt, dnn.OpArgmaxReduce),
 
     "multilinear_upsample": makeMultilinearUpsampleExecutor(dnn.DtypeFloat),
+
+    "avg_roi_pool": makeRoiPoolExecutor(dnn.DtypeFloat, dnn.OpAvgPool),
+    "max_roi_pool": makeRoiPoolExecutor(dnn.DtypeFloat, dnn.OpMaxPool),
+    "avg_roi_align": makeRoiAlignExecutor(dnn.DtypeFloat, dnn.OpAvgPool),
+    "max_roi_align": makeRoiAlignExecutor(dnn.DtypeFloat, dnn.OpMaxPool),
+    "roi_resample": makeRoiResampleExecutor(dnn.DtypeFloat),
         
     "update": executeUpdate,
 }
@@ -285,10 +385,11 @@ func executeConstant(ctx *Context, op *core.Operation) {
     }
 } 
 
-func makeUnaryExecutor(t dnn.Dtype, f dnn.UnaryOp) Executor {
+func makeUnaryExecutor(f dnn.UnaryOp) Executor {
     return func(ctx *Context, op *core.Operation) {
         x := op.GetInput("x")
         y := op.GetOutput("y")
+        t := deriveDtype(ctx, x)
         xView := mapTensor(ctx, t, x)
         yView := mapTensor(ctx, t, y)
         err := ctx.dnn.Unary(f, xView, yView)
@@ -298,14 +399,30 @@ func makeUnaryExecutor(t dnn.Dtype, f dnn.UnaryOp) Executor {
     }
 }
 
-func makeBinaryExecutor(t dnn.Dtype, r dnn.Dtype, f dnn.BinaryOp) Executor {
+func makeUnaryParamExecutor(t dnn.Dtype, f dnn.UnaryParamOp, param string) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        x := op.GetInput("x")
+        y := op.GetOutput("y")
+        alpha := op.GetAttrib(param).Scalar()
+        xView := mapTensor(ctx, t, x)
+        yView := mapTensor(ctx, t, y)
+        err := ctx.dnn.UnaryParam(f, xView, yView, alpha)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeBinaryExecutor(f dnn.BinaryOp) Executor {
     return func(ctx *Context, op *core.Operation) {
         x := op.GetInput("x")
         y := op.GetInput("y")
         z := op.GetOutput("z")
+        t := deriveDtype(ctx, x, y)
+        r := deriveDtype(ctx, z)
         xView := mapTensor(ctx, t, x)
         yView := mapTensor(ctx, t, y)
-        zView := mapTensor(ctx, t, z)
+        zView := mapTensor(ctx, r, z)
         err := ctx.dnn.Binary(f, xView, yView, zView)
         if err != nil {
             signalError(err)
@@ -313,6 +430,34 @@ func makeBinaryExecutor(t dnn.Dtype, r dnn.Dtype, f dnn.BinaryOp) Executor {
     }
 }
 
+func executePrelu(ctx *Context, op *core.Operation) {
+    x := op.GetInput("x")
+    alpha := op.GetInput("alpha")
+    y := op.GetOutput("y")
+    xView := mapTensor(ctx, dnn.DtypeFloat, x)
+    alphaView := mapTensor(ctx, dnn.DtypeFloat, alpha)
+    yView := mapTensor(ctx, dnn.DtypeFloat, y)
+    err := ctx.dnn.Binary(dnn.OpPrelu, xView, alphaView, yView)
+    if err != nil {
+        signalError(err)
+    }
+}
+
+func executeClamp(ctx *Context, op *core.Operation) {
+    x := op.GetInput("x")
+    a := op.GetInput("a")
+    b := op.GetInput("b")
+    y := op.GetOutput("y")
+    xView := mapTensor(ctx, dnn.DtypeFloat, x)
+    aView := mapTensor(ctx, dnn.DtypeFloat, a)
+    bView := mapTensor(ctx, dnn.DtypeFloat, b)
+    yView := mapTensor(ctx, dnn.DtypeFloat, y)
+    err := ctx.dnn.Clamp(xView, aView, bView, yView)
+    if err != nil {
+        signalError(err)
+    }
+}
+
 func makeReduceExecutor(t dnn.Dtype, f dnn.ReduceOp) Executor {
     return func(ctx *Context, op *core.Operation) {
         input := op.GetInput("input")
@@ -326,13 +471,26 @@ func makeReduceExecutor(t dnn.Dtype, f dnn.ReduceOp) Executor {
     }
 }
 
+func executeMoments(ctx *Context, op *core.Operation) {
+    input := op.GetInput("input")
+    mean := op.GetOutput("mean")
+    variance := op.GetOutput("variance")
+    inputView := mapTensor(ctx, dnn.DtypeFloat, input)
+    meanView := mapTensor(ctx, dnn.DtypeFloat, mean)
+    varianceView := mapTensor(ctx, dnn.DtypeFloat, variance)
+    err := ctx.dnn.Moments(inputView, meanView, varianceView)
+    if err != nil {
+        signalError(err)
+    }
+}
+
 func executeSelect(ctx *Context, op *core.Operation) {
     t := mapOpDtype(op)
     c := op.GetInput("condition")
     x := op.GetInput("true_value")
     y := op.GetInput("false_value")
     z := op.GetOutput("output")
-    cView := mapTensor(ctx, t, c)
+    cView := mapTensor(ctx, dnn.DtypeBool, c)
     xView := mapTensor(ctx, t, x)
     yView := mapTensor(ctx, t, y)
     zView := mapTensor(ctx, t, z)
@@ -352,12 +510,7 @@ func makeConvExecutor(transposed bool, t dnn.Dtype) Executor {
         stride := op.GetAttrib("stride")
         dilation := op.GetAttrib("dilation")
         groups := op.GetAttrib("groups").Integer()
-        border := op.GetAttrib("border").String()
-        if border != "constant" {
-            core.RuntimeError(
-                "operation not implemented: %s with border = '%s'", 
-                    op.Name(), border)
-        }
+        border := mapBorder(op.GetAttrib("border").String())
         var inputView, outputView dnn.Tensor
         if transposed {
             inputView = mapTensor(ctx, t, output)
@@ -405,7 +558,8 @@ func makeConvExecutor(transposed bool, t dnn.Dtype) Executor {
                     outputView,
                     paddingShape, 
                     strideShape, 
-                    dilationShape)
+                    dilationShape,
+                    border)
             if err != nil {
                 signalError(err)
             }
@@ -419,7 +573,8 @@ func makeConvExecutor(transposed bool, t dnn.Dtype) Executor {
                     outputView,
                     paddingShape, 
                     strideShape, 
-                    dilationShape)
+                    dilationShape,
+                    border)
             if err != nil {
                 signalError(err)
             }
@@ -434,7 +589,8 @@ func makeConvExecutor(transposed bool, t dnn.Dtype) Executor {
                     paddingShape, 
                     strideShape, 
                     dilationShape, 
-                    groups)
+                    groups,
+                    border)
             if err != nil {
                 signalError(err)
             }
@@ -463,17 +619,7 @@ func makePoolExecutor(transposed bool, t dnn.Dtype) Executor {
         }
         input := op.GetInput("input")
         output := op.GetOutput("output")
-        size := op.GetAttrib("size")
-        padding := op.GetAttrib("padding")
-        stride := op.GetAttrib("stride")
-        dilation := op.GetAttrib("dilation")
-        border := op.GetAttrib("border").String()
-        if border != "constant" && border != "ignore" {
-            core.RuntimeError(
-                "operation not implemented: %s with border = '%s'", 
-                    op.Name(), border)
-        }
-        includeBorder := (border != "ignore")
+        border := mapBorder(op.GetAttrib("border").String())
         var inputView, outputView dnn.Tensor
         if transposed {
             inputView = mapTensor(ctx, t, output)
@@ -483,33 +629,9 @@ func makePoolExecutor(transposed bool, t dnn.Dtype) Executor {
             outputView = mapTensor(ctx, t, output)
         }
         d := inputView.Rank()
-        checkSupportedRank(op.Name(), d, 5)
-        sizeShape := extractItems(size)
-        var strideShape core.Shape
-        if stride.Size() != 0 {
-            strideShape = extractItems(stride)
-        } else {
-            strideShape = makeSingletonShape(d)
-        }
-        var dilationShape core.Shape
-        if dilation.Size() != 0 {
-            dilationShape = extractItems(dilation)
-        } else {
-            dilationShape = makeSingletonShape(d)
-        }
-        var paddingShape core.Shape
-        if padding.Size() != 0 {
-            paddingShape = extractItems(padding)
-        } else {
-            paddingShape =
-                makePadding(
-                    d, 
-                    inputView.Shape(), 
-                    outputView.Shape(),
-                    sizeShape, 
-                    strideShape,
-                    dilationShape)
-        }
+        checkSupportedRank(op.Name(), d, dnn.MaxRank)
+        sizeShape, paddingShape, strideShape, dilationShape := 
+            makePoolShapes(op, inputView.Shape(), outputView.Shape())
         err :=
             ctx.dnn.Pool(
                 f, 
@@ -520,7 +642,73 @@ func makePoolExecutor(transposed bool, t dnn.Dtype) Executor {
                 paddingShape, 
                 strideShape, 
                 dilationShape,
-                includeBorder)
+                border)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func executeMaxPoolWithIndex(ctx *Context, op *core.Operation) {
+    t := dnn.DtypeFloat
+    input := op.GetInput("input")
+    index := op.GetOutput("index")
+    border := mapBorder(op.GetAttrib("border").String())
+    inputView := mapTensor(ctx, t, input)
+    indexView := mapTensor(ctx, dnn.DtypeInt, index)
+    var outputView dnn.Tensor
+    if op.Name() == "max_pool_with_index" {
+        outputView = mapTensor(ctx, t, op.GetOutput("output"))
+    }
+    d := inputView.Rank()
+    checkSupportedRank(op.Name(), d, dnn.MaxRank)
+    sizeShape, paddingShape, strideShape, dilationShape := 
+        makePoolShapes(op, inputView.Shape(), indexView.Shape())
+    err :=
+        ctx.dnn.MaxPoolWithIndex(
+            inputView,
+            outputView,
+            indexView,
+            sizeShape,
+            paddingShape,
+            strideShape,
+            dilationShape,
+            border)
+    if err != nil {
+        signalError(err)
+    }
+}
+
+func makeSampleExecutor(transposed bool, t dnn.Dtype) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        index := op.GetInput("index")
+        output := op.GetOutput("output")
+        border := mapBorder(op.GetAttrib("border").String())
+        var inputView, outputView dnn.Tensor
+        if transposed {
+            inputView = mapTensor(ctx, t, output)
+            outputView = mapTensor(ctx, t, input)
+        } else {
+            inputView = mapTensor(ctx, t, input)
+            outputView = mapTensor(ctx, t, output)
+        }
+        indexView := mapTensor(ctx, dnn.DtypeInt, index)
+        d := inputView.Rank()
+        checkSupportedRank(op.Name(), d, dnn.MaxRank)
+        sizeShape, paddingShape, strideShape, dilationShape := 
+            makePoolShapes(op, inputView.Shape(), outputView.Shape())
+        err :=
+            ctx.dnn.Sample(
+                transposed,
+                inputView,
+                indexView,
+                outputView,
+                sizeShape,
+                paddingShape,
+                strideShape,
+                dilationShape,
+                border)
         if err != nil {
             signalError(err)
         }
@@ -547,7 +735,7 @@ func executeTranspose(ctx *Context, op *core.Operation) {
     inputView := mapTensor(ctx, t, input)
     outputView := mapTensor(ctx, t, output)
     rank := inputView.Rank()
-    checkSupportedRank(op.Name(), rank, 5)
+    checkSupportedRank(op.Name(), rank, dnn.MaxRank)
     perm := make([]int, rank)
     size := axes.Size()
     for i := 0; i < size; i++ {
@@ -598,32 +786,67 @@ func executeSplit(ctx *Context, op *core.Operation) {
     }
 }
 
-func makePadExecutor(t dnn.Dtype) Executor {
-    return func(ctx *Context, op *core.Operation) {
-        input := op.GetInput("input")
-        output := op.GetOutput("output")
-        padding := op.GetAttrib("padding")
-        border := op.GetAttrib("border").String()
-        value := op.GetAttrib("value")
-        inputView := mapTensor(ctx, t, input)
-        outputView := mapTensor(ctx, t, output)
-        paddingShape := extractItems(padding)
-        d := inputView.Rank()
-        checkSupportedRank(op.Name(), d, 5)
-        switch border {
-        case "constant":
-            err := ctx.dnn.PadConstant(inputView, outputView, paddingShape, mapValue(value, t))
-            if err != nil {
-                signalError(err)
-            }
-        case "replicate":
-            err := ctx.dnn.PadReplicate(inputView, outputView, paddingShape)
-            if err != nil {
-                signalError(err)
-            }
-        default:
-            core.RuntimeError("operation not implemented: pad with border == '%s'", border)
+func executeCopyN(ctx *Context, op *core.Operation) {
+    t := mapOpDtype(op)
+    x := op.GetInput("x")
+    y := op.GetOutput("y")
+    xView := mapTensor(ctx, t, x)
+    size := y.Size()
+    for i := 0; i < size; i++ {
+        yView := mapTensor(ctx, t, y.At(i))
+        err := ctx.dnn.Copy(xView, yView)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func executeAddN(ctx *Context, op *core.Operation) {
+    x := op.GetInput("x")
+    y := op.GetOutput("y")
+    size := x.Size()
+    v := make([]dnn.Tensor, size)
+    for i := 0; i < size; i++ {
+        v[i] = mapTensor(ctx, dnn.DtypeFloat, x.At(i))
+    }
+    yView := mapTensor(ctx, dnn.DtypeFloat, y)
+    err := ctx.dnn.Accumulate(v, yView)
+    if err != nil {
+        signalError(err)
+    }
+}
+
+func executePad(ctx *Context, op *core.Operation) {
+    input := op.GetInput("input")
+    output := op.GetOutput("output")
+    padding := op.GetAttrib("padding")
+    border := op.GetAttrib("border").String()
+    value := op.GetAttrib("value")
+    t := deriveDtype(ctx, input)
+    inputView := mapTensor(ctx, t, input)
+    outputView := mapTensor(ctx, t, output)
+    paddingShape := extractItems(padding)
+    d := inputView.Rank()
+    checkSupportedRank(op.Name(), d, dnn.MaxRank)
+    switch border {
+    case "constant":
+        err := ctx.dnn.PadConstant(inputView, outputView, paddingShape, convertValue(value, t))
+        if err != nil {
+            signalError(err)
+        }
+    case "replicate":
+        err := ctx.dnn.PadReplicate(inputView, outputView, paddingShape)
+        if err != nil {
+            signalError(err)
         }
+    case "reflect", "reflect-even":
+        even := (border == "reflect-even")
+        err := ctx.dnn.PadReflect(inputView, outputView, paddingShape, even)
+        if err != nil {
+            signalError(err)
+        }
+    default:
+        core.RuntimeError("operation not implemented: pad with border == '%s'", border)
     }
 }
 
@@ -634,7 +857,7 @@ func executeTile(ctx *Context, op *core.Operation) {
     inputView := mapTensor(ctx, t, input)
     outputView := mapTensor(ctx, t, output)
     d := inputView.Rank()
-    checkSupportedRank(op.Name(), d, 5)
+    checkSupportedRank(op.Name(), d, dnn.MaxRank)
     err := ctx.dnn.Tile(inputView, outputView)
     if err != nil {
         signalError(err)
@@ -650,7 +873,7 @@ func executeSlice(ctx *Context, op *core.Operation) {
     inputView := mapTensor(ctx, t, input)
     outputView := mapTensor(ctx, t, output)
     d := inputView.Rank()
-    checkSupportedRank(op.Name(), d, 5)
+    checkSupportedRank(op.Name(), d, dnn.MaxRank)
     size := axes.Size()
     offset := make([]int, d)
     for i := 0; i < size; i++ {
@@ -663,6 +886,124 @@ func executeSlice(ctx *Context, op *core.Operation) {
     }
 }
 
+func makeLocalNormExecutor(t dnn.Dtype, f dnn.LocalNormOp) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        output := op.GetOutput("output")
+        size := op.GetAttrib("size")
+        alpha := float32(0.0)
+        beta := float32(0.0)
+        bias := float32(0.0)
+        epsilon := float32(0.0)
+        switch f {
+        case dnn.OpLocalResponseNorm:
+            alpha = op.GetAttrib("alpha").Scalar()
+            beta = op.GetAttrib("beta").Scalar()
+            bias = op.GetAttrib("bias").Scalar()
+        case dnn.OpLocalVarianceNorm, dnn.OpLocalContrastNorm:
+            bias = op.GetAttrib("bias").Scalar()
+            epsilon = op.GetAttrib("epsilon").Scalar()
+        }
+        inputView := mapTensor(ctx, t, input)
+        outputView := mapTensor(ctx, t, output)
+        sizeShape := extractItems(size)
+        err := 
+            ctx.dnn.LocalNorm(
+                f, 
+                inputView, 
+                outputView, 
+                sizeShape, 
+                alpha, 
+                beta, 
+                bias, 
+                epsilon)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeNormExecutor(t dnn.Dtype, f dnn.NormOp) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        output := op.GetOutput("output")
+        axes := op.GetAttrib("axes")
+        bias := op.GetAttrib("bias").Scalar()
+        epsilon := op.GetAttrib("epsilon").Scalar()
+        inputView := mapTensor(ctx, t, input)
+        outputView := mapTensor(ctx, t, output)
+        axesShape := extractItems(axes)
+        err := ctx.dnn.Norm(f, inputView, outputView, axesShape, bias, epsilon)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeBatchNormExecutor(t dnn.Dtype) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        mean := op.GetInput("mean")
+        variance := op.GetInput("variance")
+        offset := op.GetInput("offset")
+        scale := op.GetInput("scale")
+        output := op.GetOutput("output")
+        epsilon := op.GetAttrib("epsilon").Scalar()
+        inputView := mapTensor(ctx, t, input)
+        meanView := mapTensor(ctx, t, mean)
+        varianceView := mapTensor(ctx, t, variance)
+        offsetView := mapTensor(ctx, t, offset)
+        scaleView := mapTensor(ctx, t, scale)
+        outputView := mapTensor(ctx, t, output)
+        err := 
+            ctx.dnn.BatchNorm(
+                inputView,
+                meanView,
+                varianceView,
+                offsetView,
+                scaleView,
+                outputView,
+                epsilon)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeLinearQuantizeExecutor(t dnn.Dtype) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        x := op.GetInput("x")
+        min := op.GetInput("min")
+        max := op.GetInput("max")
+        y := op.GetOutput("y")
+        bits := op.GetAttrib("bits").Integer()
+        xView := mapTensor(ctx, t, x)
+        minView := mapTensor(ctx, t, min)
+        maxView := mapTensor(ctx, t, max)
+        yView := mapTensor(ctx, t, y)
+        err := ctx.dnn.LinearQuantize(xView, minView, maxView, yView, bits)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeLogarithmicQuantizeExecutor(t dnn.Dtype) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        x := op.GetInput("x")
+        max := op.GetInput("max")
+        y := op.GetOutput("y")
+        bits := op.GetAttrib("bits").Integer()
+        xView := mapTensor(ctx, t, x)
+        maxView := mapTensor(ctx, t, max)
+        yView := mapTensor(ctx, t, y)
+        err := ctx.dnn.LogarithmicQuantize(xView, maxView, yView, bits)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
 func makeMatmulExecutor(t dnn.Dtype) Executor {
     return func(ctx *Context, op *core.Operation) {
         a := op.GetInput("A")
@@ -706,11 +1047,8 @@ func makeSoftmaxExecutor(t dnn.Dtype) Executor {
         axes := op.GetAttrib("axes")
         inputView := mapTensor(ctx, t, input)
         outputView := mapTensor(ctx, t, output)
-        if axes.Size() != 1 {
-            core.RuntimeError("operation not implemented: softmax with multiple axes")
-        }
-        axis := axes.At(0).Integer()
-        err := ctx.dnn.Softmax(inputView, outputView, axis)
+        axesShape := extractItems(axes)
+        err := ctx.dnn.Softmax(inputView, outputView, axesShape)
         if err != nil {
             signalError(err)
         }
@@ -724,11 +1062,8 @@ func makeArgReduceExecutor(t dnn.Dtype, i dnn.Dtype, f dnn.ArgReduceOp) Executor
         axes := op.GetAttrib("axes")
         inputView := mapTensor(ctx, t, input)
         outputView := mapTensor(ctx, i, output)
-        if axes.Size() != 1 {
-            core.RuntimeError("operation not implemented: argmax_reduce with multiple axes")
-        }
-        axis := axes.At(0).Integer()
-        err := ctx.dnn.ArgReduce(f, inputView, outputView, axis)
+        axesShape := extractItems(axes)
+        err := ctx.dnn.ArgReduce(f, inputView, outputView, axesShape)
         if err != nil {
             signalError(err)
         }
@@ -737,8 +1072,100 @@ func makeArgReduceExecutor(t dnn.Dtype, i dnn.Dtype, f dnn.ArgReduceOp) Executor
 
 func makeMultilinearUpsampleExecutor(t dnn.Dtype) Executor {
     return func(ctx *Context, op *core.Operation) {
-        // TODO
-        core.RuntimeError("operation not implemented: %s", op.Name())
+        input := op.GetInput("input")
+        output := op.GetOutput("output")
+        factor := op.GetAttrib("factor")
+        method := op.GetAttrib("method").String()
+        border := op.GetAttrib("border").String()
+        m := mapUpsampleMethod(op, method)
+        if border != "constant" && border != "replicate" {
+            core.RuntimeError(
+                "operation not implemented: %s with border = '%s'", 
+                    op.Name(), border)
+        }
+        inputView := mapTensor(ctx, t, input)
+        outputView := mapTensor(ctx, t, output)
+        d := inputView.Rank() - 2
+        checkSupportedRank(op.Name(), d, 3)
+        factorShape := extractItems(factor)
+        err :=
+            ctx.dnn.MultilinearUpsample(
+                m,
+                mapBorder(border),
+                inputView,
+                outputView,
+                factorShape)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeRoiPoolExecutor(t dnn.Dtype, f dnn.PoolOp) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        rois := op.GetInput("rois")
+        batchIndex := op.GetInput("batch_index")
+        output := op.GetOutput("output")
+        inputView := mapTensor(ctx, t, input)
+        roisView := mapTensor(ctx, t, rois)
+        batchIndexView := mapTensor(ctx, dnn.DtypeInt, batchIndex)
+        outputView := mapTensor(ctx, t, output)
+        checkRoiRank(op.Name(), inputView.Rank())
+        err := ctx.dnn.RoiPool(f, inputView, roisView, batchIndexView, outputView)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeRoiResampleExecutor(t dnn.Dtype) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        rois := op.GetInput("rois")
+        batchIndex := op.GetInput("batch_index")
+        output := op.GetOutput("output")
+        method := op.GetAttrib("method").String()
+        m := mapUpsampleMethod(op, method)
+        inputView := mapTensor(ctx, t, input)
+        roisView := mapTensor(ctx, t, rois)
+        batchIndexView := mapTensor(ctx, dnn.DtypeInt, batchIndex)
+        outputView := mapTensor(ctx, t, output)
+        checkRoiRank(op.Name(), inputView.Rank())
+        err := ctx.dnn.RoiResample(m, inputView, roisView, batchIndexView, outputView)
+        if err != nil {
+            signalError(err)
+        }
+    }
+}
+
+func makeRoiAlignExecutor(t dnn.Dtype, f dnn.PoolOp) Executor {
+    return func(ctx *Context, op *core.Operation) {
+        input := op.GetInput("input")
+        rois := op.GetInput("rois")
+        batchIndex := op.GetInput("batch_index")
+        output := op.GetOutput("output")
+        samplingRate := op.GetAttrib("sampling_rate")
+        method := op.GetAttrib("resize_method").String()
+        m := mapUpsampleMethod(op, method)
+        inputView := mapTensor(ctx, t, input)
+        roisView := mapTensor(ctx, t, rois)
+        batchIndexView := mapTensor(ctx, dnn.DtypeInt, batchIndex)
+        outputView := mapTensor(ctx, t, output)
+        checkRoiRank(op.Name(), inputView.Rank())
+        rateShape := extractItems(samplingRate)
+        err := 
+            ctx.dnn.RoiAlign(
+                f, 
+                m, 
+                inputView, 
+                roisView, 
+                batchIndexView, 
+                outputView, 
+                rateShape)
+        if err != nil {
+            signalError(err)
+        }
     }
 }
 
@@ -764,6 +1191,12 @@ func checkSupportedRank(op string, rank int, max int) {
     }
 }
 
+func checkRoiRank(op string, rank int) {
+    if rank != 4 {
+        core.RuntimeError("operation not implemented: %s with rank = %d", op, rank)
+    }
+}
+
 func extractItems(value core.Value) core.Shape {
     size := value.Size()
     items := make(core.Shape, size)
@@ -796,6 +1229,67 @@ func makePadding(
     return padding
 }
 
+func mapUpsampleMethod(op *core.Operation, method string) dnn.UpsampleMethod {
+    switch method {
+    case "symmetric":
+        return dnn.UpsampleSymmetric
+    case "asymmetric":
+        return dnn.UpsampleAsymmetric
+    case "aligned":
+        return dnn.UpsampleAligned
+    default:
+        core.RuntimeError(
+            "operation not implemented: %s with method = '%s'", 
+                op.Name(), method)
+        return 0
+    }
+}
+
+func mapBorder(border string) dnn.Border {
+    switch border {
+    case "constant":
+        return dnn.BorderConstant
+    case "ignore":
+        return dnn.BorderIgnore
+    case "replicate":
+        return dnn.BorderReplicate
+    case "reflect":
+        return dnn.BorderReflect
+    case "reflect-even":
+        return dnn.BorderReflectEven
+    default:
+        core.RuntimeError("invalid border mode: '%s'", border)
+        return 0
+    }
+}
+
+func makePoolShapes(
+        op *core.Operation, 
+        input []int, 
+        output []int) (size core.Shape, padding core.Shape, stride core.Shape, dilation core.Shape) {
+    rank := len(input)
+    size = extractItems(op.GetAttrib("size"))
+    strideAttr := op.GetAttrib("stride")
+    if strideAttr.Size() != 0 {
+        stride = extractItems(strideAttr)
+    } else {
+        stride = makeSingletonShape(rank)
+    }
+    dilationAttr := op.GetAttrib("dilation")
+    if dilationAttr.Size() != 0 {
+        dilation = extractItems(dilationAttr)
+    } else {
+        dilation = makeSingletonShape(rank)
+    }
+    paddingAttr := op.GetAttrib("padding")
+    if paddingAttr.Size() != 0 {
+        padding = extractItems(paddingAttr)
+    } else {
+        padding = makePadding(rank, input, output, size, stride, dilation)
+    }
+    return
+}
+
 func makeSingletonShape(rank int) core.Shape {
     shape := make(core.Shape, rank)
     for i := 0; i < rank; i++ {
@@ -819,6 +1313,28 @@ func mapOpDtype(op *core.Operation) dnn.Dtype {
     }
 }
 
+// Derives dtype from the first value that refers to a tensor;
+// literal values define dtype only if no value is a tensor.
+
+func deriveDtype(ctx *Context, values ...core.Value) dnn.Dtype {
+    for _, value := range values {
+        if value.Kind() == core.ValueKindIdentifier {
+            return ctx.getTensor(value.Identifier()).Dtype()
+        }
+    }
+    switch values[0].Kind() {
+    case core.ValueKindScalar:
+        return dnn.DtypeFloat
+    case core.ValueKindInteger:
+        return dnn.DtypeInt
+    case core.ValueKindLogical:
+        return dnn.DtypeBool
+    default:
+        core.Assert(false)
+        return 0
+    }
+}
+
 func mapTensor(ctx *Context, t dnn.Dtype, value core.Value) dnn.Tensor {
     switch value.Kind() {
     case core.ValueKindIdentifier:
@@ -853,14 +1369,30 @@ func makeSingleton(ctx *Context, t dnn.Dtype, value interface{}) dnn.Tensor {
     return view
 }
 
-func mapValue(value core.Value, t dnn.Dtype) interface{} {
+// Converts literal value of any kind to dtype t, as required for
+// attributes like pad value that are declared as scalar in stdlib.
+
+func convertValue(value core.Value, t dnn.Dtype) interface{} {
+    var v float64
+    switch value.Kind() {
+    case core.ValueKindScalar:
+        v = float64(value.Scalar())
+    case core.ValueKindInteger:
+        v = float64(value.Integer())
+    case core.ValueKindLogical:
+        if value.Logical() {
+            v = 1.0
+        }
+    default:
+        core.Assert(false)
+    }
     switch t {
     case dnn.DtypeBool:
-        return value.Logical()
+        return (v != 0.0)
     case dnn.DtypeInt:
-        return value.Integer()
+        return int(v)
     case dnn.DtypeFloat:
-        return value.Scalar()
+        return float32(v)
     default:
         core.Assert(false)
         return nil